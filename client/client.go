@@ -13,6 +13,12 @@ func NewClient(clientType string, clientName string, exp *expression.Expressions
 		return NewDeluge(clientName, exp)
 	case "qbittorrent":
 		return NewQBittorrent(clientName, exp)
+	case "rtorrent":
+		return NewRTorrent(clientName, exp)
+	case "snapshot":
+		return NewSnapshot(clientName, exp)
+	case "transmission":
+		return NewTransmission(clientName, exp)
 	default:
 		break
 	}
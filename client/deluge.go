@@ -172,6 +172,7 @@ func (c *Deluge) GetTorrents() (map[string]config.Torrent, error) {
 			Path:            t.DownloadLocation,
 			TotalBytes:      t.TotalSize,
 			DownloadedBytes: t.TotalDone,
+			UploadedBytes:   delugeUploadedBytes(t),
 			State:           t.State,
 			Files:           files,
 			Downloaded:      t.TotalDone == t.TotalSize,
@@ -272,37 +273,52 @@ func (c *Deluge) GetFreeSpace() float64 {
 
 /* Filters */
 
-func (c *Deluge) ShouldIgnore(t *config.Torrent) (bool, error) {
+func (c *Deluge) ShouldIgnore(t *config.Torrent) (bool, *expression.Match, error) {
 	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Ignores)
 	if err != nil {
-		return true, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+		return true, nil, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
 	}
 
-	return match, nil
+	return true, match.Match("ignore"), nil
 }
 
-func (c *Deluge) ShouldRemove(t *config.Torrent) (bool, error) {
+func (c *Deluge) ShouldRemove(t *config.Torrent) (bool, *expression.Match, error) {
 	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Removes)
 	if err != nil {
-		return false, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+		return false, nil, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
 	}
 
-	return match, nil
+	return true, match.Match("remove"), nil
 }
 
-func (c *Deluge) ShouldRelabel(t *config.Torrent) (string, bool, error) {
+func (c *Deluge) ShouldRelabel(t *config.Torrent) (string, bool, *expression.Match, error) {
 	for _, label := range c.exp.Labels {
 		// check update
 		match, err := expression.CheckTorrentAllMatch(t, label.Updates)
 		if err != nil {
-			return "", false, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+			return "", false, nil, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
 		} else if !match {
 			continue
 		}
 
 		// we should re-label
-		return label.Name, true, nil
+		return label.Name, true, label.Match(), nil
 	}
 
-	return "", false, nil
+	return "", false, nil, nil
+}
+
+/* Private */
+
+// delugeUploadedBytes estimates the uploaded bytes from the ratio, as the client library does not expose them.
+func delugeUploadedBytes(t *delugeclient.TorrentStatus) int64 {
+	if t.Ratio <= 0 {
+		return 0
+	}
+
+	return int64(float64(t.Ratio) * float64(t.TotalDone))
 }
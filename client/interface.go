@@ -2,6 +2,7 @@ package client
 
 import (
 	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
 )
 
 type Interface interface {
@@ -16,7 +17,13 @@ type Interface interface {
 	LoadLabelPathMap() error
 	LabelPathMap() map[string]string
 
-	ShouldIgnore(*config.Torrent) (bool, error)
-	ShouldRemove(*config.Torrent) (bool, error)
-	ShouldRelabel(*config.Torrent) (string, bool, error)
+	ShouldIgnore(*config.Torrent) (bool, *expression.Match, error)
+	ShouldRemove(*config.Torrent) (bool, *expression.Match, error)
+	ShouldRelabel(*config.Torrent) (string, bool, *expression.Match, error)
+}
+
+// SavePathInterface is implemented by clients saving torrents without a label to a default path,
+// loaded by LoadLabelPathMap.
+type SavePathInterface interface {
+	DefaultSavePath() string
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/autobrr/tqm/config"
@@ -20,6 +21,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+/* Const */
+
+const (
+	// default number of concurrent requests used to retrieve torrent details
+	qbittorrentDefaultWorkers = 10
+)
+
 /* Struct */
 
 type QBittorrent struct {
@@ -27,6 +35,7 @@ type QBittorrent struct {
 	User                      string
 	Password                  string
 	EnableAutoTmmAfterRelabel bool
+	Workers                   int
 
 	// internal
 	log        *logrus.Entry
@@ -34,7 +43,8 @@ type QBittorrent struct {
 	client     *qbit.Client
 
 	// need to be loaded by LoadLabelPathMap
-	labelPathMap map[string]string
+	labelPathMap    map[string]string
+	defaultSavePath string
 
 	// set by cmd handler
 	freeSpaceGB  float64
@@ -80,6 +90,141 @@ func NewQBittorrent(name string, exp *expression.Expressions) (TagInterface, err
 	return &tc, nil
 }
 
+/* Private */
+
+type qbittorrentTorrentDetails struct {
+	savePath    string
+	addedOn     int64
+	seedingTime int64
+	files       qbit.TorrentFiles
+	trackers    []qbit.TorrentTracker
+}
+
+func (c *QBittorrent) workers() int {
+	if c.Workers > 0 {
+		return c.Workers
+	}
+
+	return qbittorrentDefaultWorkers
+}
+
+// needsProperties determines whether the torrent list lacks details only available from the torrent properties.
+func (c *QBittorrent) needsProperties(t qbit.Torrent) bool {
+	// save_path is missing from the torrent list of older webapi versions
+	if t.SavePath == "" {
+		return true
+	}
+
+	// seeding_time is missing from the torrent list of older webapi versions
+	if t.SeedingTime == 0 && t.CompletionOn > 0 && t.Progress >= 1 &&
+		time.Since(time.Unix(t.CompletionOn, 0)) > time.Minute {
+		return true
+	}
+
+	return false
+}
+
+// getTorrentDetails retrieves the files and trackers (and properties, when needed) of the torrents
+// using a bounded pool of workers.
+func (c *QBittorrent) getTorrentDetails(torrents []qbit.Torrent) (map[string]*qbittorrentTorrentDetails, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu              sync.Mutex
+		wg              sync.WaitGroup
+		firstErr        error
+		propertiesCount int
+	)
+
+	details := make(map[string]*qbittorrentTorrentDetails, len(torrents))
+	jobs := make(chan qbit.Torrent)
+
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	for i := 0; i < c.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for t := range jobs {
+				td := &qbittorrentTorrentDetails{
+					savePath:    t.SavePath,
+					addedOn:     t.AddedOn,
+					seedingTime: t.SeedingTime,
+				}
+
+				// fallback to torrent properties
+				if c.needsProperties(t) {
+					p, err := c.client.GetTorrentPropertiesCtx(ctx, t.Hash)
+					if err != nil {
+						fail(fmt.Errorf("get torrent properties: %v: %w", t.Hash, err))
+						continue
+					}
+
+					td.savePath = p.SavePath
+					td.addedOn = int64(p.AdditionDate)
+					td.seedingTime = int64(p.SeedingTime)
+
+					mu.Lock()
+					propertiesCount++
+					mu.Unlock()
+				}
+
+				ts, err := c.client.GetTorrentTrackersCtx(ctx, t.Hash)
+				if err != nil {
+					fail(fmt.Errorf("get torrent trackers: %v: %w", t.Hash, err))
+					continue
+				}
+				td.trackers = ts
+
+				tf, err := c.client.GetFilesInformationCtx(ctx, t.Hash)
+				if err != nil {
+					fail(fmt.Errorf("get torrent files: %v: %w", t.Hash, err))
+					continue
+				}
+				if tf != nil {
+					td.files = *tf
+				}
+
+				mu.Lock()
+				details[t.Hash] = td
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// queue torrents
+queue:
+	for _, t := range torrents {
+		select {
+		case jobs <- t:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if propertiesCount > 0 {
+		c.log.Debugf("Retrieved properties for %d torrents missing details in the torrent list", propertiesCount)
+	}
+
+	return details, nil
+}
+
 /* Interface  */
 
 func (c *QBittorrent) Type() string {
@@ -116,6 +261,7 @@ func (c *QBittorrent) LoadLabelPathMap() error {
 		return fmt.Errorf("get categories: %w", err)
 	}
 
+	c.defaultSavePath = p.SavePath
 	c.labelPathMap = make(map[string]string)
 	for _, cat := range cats {
 		if cat.SavePath == "" {
@@ -138,42 +284,40 @@ func (c *QBittorrent) LabelPathMap() map[string]string {
 	return c.labelPathMap
 }
 
+func (c *QBittorrent) DefaultSavePath() string {
+	return c.defaultSavePath
+}
+
 func (c *QBittorrent) GetTorrents() (map[string]config.Torrent, error) {
 	// retrieve torrents from client
 	c.log.Tracef("Retrieving torrents...")
+	start := time.Now()
 	t, err := c.client.GetTorrents(qbit.TorrentFilterOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("get torrents: %w", err)
 	}
-	c.log.Tracef("Retrieved %d torrents", len(t))
+	c.log.Debugf("Retrieved %d torrents in %s", len(t), time.Since(start))
+
+	// retrieve additional torrent details
+	start = time.Now()
+	details, err := c.getTorrentDetails(t)
+	if err != nil {
+		return nil, err
+	}
+	c.log.Debugf("Retrieved files and trackers for %d torrents in %s (%d workers)", len(details),
+		time.Since(start), c.workers())
 
 	// build torrent list
 	torrents := make(map[string]config.Torrent)
 	for _, t := range t {
 		t := t
-
-		// get additional torrent details
-		//td, err := c.client.Torrent.GetProperties(t.Hash)
-		td, err := c.client.GetTorrentProperties(t.Hash)
-		if err != nil {
-			return nil, fmt.Errorf("get torrent properties: %v: %w", t.Hash, err)
-		}
-
-		ts, err := c.client.GetTorrentTrackers(t.Hash)
-		if err != nil {
-			return nil, fmt.Errorf("get torrent trackers: %v: %w", t.Hash, err)
-		}
-
-		tf, err := c.client.GetFilesInformation(t.Hash)
-		if err != nil {
-			return nil, fmt.Errorf("get torrent files: %v: %w", t.Hash, err)
-		}
+		td := details[t.Hash]
 
 		// parse tracker details
 		trackerName := ""
 		trackerStatus := ""
 
-		for _, tracker := range ts {
+		for _, tracker := range td.trackers {
 			// skip disabled trackers
 			if strings.Contains(tracker.Url, "[DHT]") || strings.Contains(tracker.Url, "[LSD]") ||
 				strings.Contains(tracker.Url, "[PeX]") {
@@ -187,14 +331,14 @@ func (c *QBittorrent) GetTorrents() (map[string]config.Torrent, error) {
 		}
 
 		// added time
-		addedTimeSecs := int64(time.Since(time.Unix(int64(td.AdditionDate), 0)).Seconds())
+		addedTimeSecs := int64(time.Since(time.Unix(td.addedOn, 0)).Seconds())
 
-		seedingTime := time.Duration(td.SeedingTime) * time.Second
+		seedingTime := time.Duration(td.seedingTime) * time.Second
 
 		// torrent files
 		var files []string
-		for _, f := range *tf {
-			files = append(files, filepath.Join(td.SavePath, f.Name))
+		for _, f := range td.files {
+			files = append(files, filepath.Join(td.savePath, f.Name))
 		}
 
 		// create torrent
@@ -207,9 +351,10 @@ func (c *QBittorrent) GetTorrents() (map[string]config.Torrent, error) {
 		torrent := config.Torrent{
 			Hash:            t.Hash,
 			Name:            t.Name,
-			Path:            td.SavePath,
+			Path:            td.savePath,
 			TotalBytes:      t.Size,
-			DownloadedBytes: td.TotalDownloaded,
+			DownloadedBytes: t.Downloaded,
+			UploadedBytes:   t.Uploaded,
 			State:           string(t.State),
 			Files:           files,
 			Tags:            tags,
@@ -224,7 +369,7 @@ func (c *QBittorrent) GetTorrents() (map[string]config.Torrent, error) {
 				"uploading",
 				"stalledUP",
 			}, string(t.State), true),
-			Ratio:          float32(td.ShareRatio),
+			Ratio:          float32(t.Ratio),
 			AddedSeconds:   addedTimeSecs,
 			AddedHours:     float32(addedTimeSecs) / 60 / 60,
 			AddedDays:      float32(addedTimeSecs) / 60 / 60 / 24,
@@ -232,8 +377,8 @@ func (c *QBittorrent) GetTorrents() (map[string]config.Torrent, error) {
 			SeedingHours:   float32(seedingTime.Seconds()) / 60 / 60,
 			SeedingDays:    float32(seedingTime.Seconds()) / 60 / 60 / 24,
 			Label:          t.Category,
-			Seeds:          int64(td.SeedsTotal),
-			Peers:          int64(td.PeersTotal),
+			Seeds:          t.NumComplete,
+			Peers:          t.NumIncomplete,
 			// free space
 			FreeSpaceGB:  c.GetFreeSpace,
 			FreeSpaceSet: c.freeSpaceSet,
@@ -370,39 +515,43 @@ func (c *QBittorrent) GetFreeSpace() float64 {
 
 /* Filters */
 
-func (c *QBittorrent) ShouldIgnore(t *config.Torrent) (bool, error) {
+func (c *QBittorrent) ShouldIgnore(t *config.Torrent) (bool, *expression.Match, error) {
 	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Ignores)
 	if err != nil {
-		return true, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+		return true, nil, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
 	}
 
-	return match, nil
+	return true, match.Match("ignore"), nil
 }
 
-func (c *QBittorrent) ShouldRemove(t *config.Torrent) (bool, error) {
+func (c *QBittorrent) ShouldRemove(t *config.Torrent) (bool, *expression.Match, error) {
 	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Removes)
 	if err != nil {
-		return false, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+		return false, nil, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
 	}
 
-	return match, nil
+	return true, match.Match("remove"), nil
 }
 
-func (c *QBittorrent) ShouldRelabel(t *config.Torrent) (string, bool, error) {
+func (c *QBittorrent) ShouldRelabel(t *config.Torrent) (string, bool, *expression.Match, error) {
 	for _, label := range c.exp.Labels {
 		// check update
 		match, err := expression.CheckTorrentAllMatch(t, label.Updates)
 		if err != nil {
-			return "", false, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+			return "", false, nil, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
 		} else if !match {
 			continue
 		}
 
 		// we should re-label
-		return label.Name, true, nil
+		return label.Name, true, label.Match(), nil
 	}
 
-	return "", false, nil
+	return "", false, nil, nil
 }
 
 func (c *QBittorrent) ShouldRetag(t *config.Torrent) (RetagInfo, bool, error) {
@@ -421,10 +570,12 @@ func (c *QBittorrent) ShouldRetag(t *config.Torrent) (RetagInfo, bool, error) {
 		if containTag && !match && (tagMode == "remove" || tagMode == "full") {
 			// we should remove the tag
 			retagInfo.Remove = append(retagInfo.Remove, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
 		}
 		if !containTag && match && (tagMode == "add" || tagMode == "full") {
 			// we should add the tag
 			retagInfo.Add = append(retagInfo.Add, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
 		}
 	}
 
@@ -0,0 +1,634 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/xmlrpc"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+/* Const */
+
+const (
+	// number of calls batched into a single system.multicall request
+	rtorrentMulticallBatchSize = 250
+
+	// number of files removed by a single rm command
+	rtorrentRemoveBatchSize = 100
+)
+
+/* Vars */
+
+var (
+	// fields retrieved for every torrent via d.multicall2, order is relied upon by GetTorrents
+	rtorrentTorrentFields = []interface{}{
+		"d.hash=",
+		"d.name=",
+		"d.directory=",
+		"d.is_multi_file=",
+		"d.size_bytes=",
+		"d.completed_bytes=",
+		"d.complete=",
+		"d.state=",
+		"d.is_active=",
+		"d.hashing=",
+		"d.ratio=",
+		"d.custom1=",
+		"d.custom=addtime",
+		"d.custom=seedingtime",
+		"d.timestamp.started=",
+		"d.timestamp.finished=",
+		"d.message=",
+		"d.up.total=",
+	}
+)
+
+/* Struct */
+
+type RTorrent struct {
+	Url      *string `validate:"required"`
+	User     string
+	Password string
+
+	// internal
+	log        *logrus.Entry
+	clientType string
+	client     *xmlrpc.Client
+
+	// set by cmd handler
+	freeSpaceGB  float64
+	freeSpaceSet bool
+
+	// internal compiled filters
+	exp *expression.Expressions
+}
+
+/* Initializer */
+
+func NewRTorrent(name string, exp *expression.Expressions) (Interface, error) {
+	tc := RTorrent{
+		log:        logger.GetLogger(name),
+		clientType: "rTorrent",
+		exp:        exp,
+	}
+
+	// load config
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s", config.Delimiter, name), &tc); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	// validate config
+	if errs := config.ValidateStruct(tc); errs != nil {
+		return nil, fmt.Errorf("validate config: %v", errs)
+	}
+
+	// init client
+	tc.client = xmlrpc.NewClient(*tc.Url, tc.User, tc.Password, 5*time.Minute)
+
+	return &tc, nil
+}
+
+/* Private */
+
+func rtorrentString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	}
+
+	return ""
+}
+
+func rtorrentInt(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case float64:
+		return int64(val)
+	case bool:
+		if val {
+			return 1
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64); err == nil {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func rtorrentLabel(v string) string {
+	// rutorrent stores labels url-encoded
+	if l, err := url.PathUnescape(v); err == nil {
+		return l
+	}
+
+	return v
+}
+
+func rtorrentState(state int64, active int64, hashing int64, complete int64) string {
+	switch {
+	case hashing != 0:
+		return "checking"
+	case state == 0:
+		return "stopped"
+	case active == 0:
+		return "paused"
+	case complete != 0:
+		return "seeding"
+	default:
+		return "downloading"
+	}
+}
+
+// rtorrentTrackerStatus returns the status of a tracker, empty unless its latest announce failed.
+// rtorrent only keeps the failure reason of the latest tracker error in the torrent message.
+func rtorrentTrackerStatus(successTime int64, failedTime int64, message string) string {
+	if failedTime == 0 || successTime >= failedTime {
+		return ""
+	}
+
+	if reason, ok := strings.CutPrefix(message, "Tracker: "); ok {
+		// e.g. Tracker: [Failure reason "Unregistered torrent"]
+		return strings.TrimSuffix(strings.TrimPrefix(reason, "["), "]")
+	}
+
+	return "Tracker announce failed"
+}
+
+// getTorrentDataPaths returns the files of a torrent and, for multi file torrents,
+// the folders containing them within the torrent folder, deepest first.
+func (c *RTorrent) getTorrentDataPaths(hash string) ([]string, []string, error) {
+	r, err := c.client.Multicall([][]interface{}{
+		{"d.directory", hash},
+		{"d.is_multi_file", hash},
+		{"f.multicall", hash, "", "f.path="},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, v := range r {
+		if f, ok := v.(*xmlrpc.Fault); ok {
+			return nil, nil, f
+		}
+	}
+
+	// directory of multi file torrents is the torrent folder itself
+	directory := filepath.Clean(rtorrentString(r[0]))
+	multiFile := rtorrentInt(r[1]) != 0
+	if !filepath.IsAbs(directory) || directory == filepath.Dir(directory) {
+		return nil, nil, fmt.Errorf("refusing to delete data of torrent in directory: %q", directory)
+	}
+
+	fileRows, _ := r[2].([]interface{})
+	files := make([]string, 0, len(fileRows))
+	folders := make(map[string]struct{})
+
+	for _, fr := range fileRows {
+		f, ok := fr.([]interface{})
+		if !ok || len(f) == 0 {
+			continue
+		}
+
+		path := filepath.Join(directory, rtorrentString(f[0]))
+		if !strings.HasPrefix(path, directory+string(filepath.Separator)) {
+			return nil, nil, fmt.Errorf("refusing to delete file outside of torrent directory: %q", path)
+		}
+		files = append(files, path)
+
+		if !multiFile {
+			// single file torrents share their directory with other torrents
+			continue
+		}
+
+		for dir := filepath.Dir(path); dir != filepath.Dir(directory); dir = filepath.Dir(dir) {
+			folders[dir] = struct{}{}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, nil, errors.New("torrent has no files")
+	}
+
+	dirs := make([]string, 0, len(folders))
+	for dir := range folders {
+		dirs = append(dirs, dir)
+	}
+
+	// deepest first, so parents are empty by the time they are removed
+	sort.Slice(dirs, func(i, j int) bool {
+		if di, dj := strings.Count(dirs[i], string(filepath.Separator)), strings.Count(dirs[j], string(filepath.Separator)); di != dj {
+			return di > dj
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	return files, dirs, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+	}
+
+	return out
+}
+
+// multicall executes the calls in batches of rtorrentMulticallBatchSize.
+func (c *RTorrent) multicall(calls [][]interface{}) ([]interface{}, error) {
+	results := make([]interface{}, 0, len(calls))
+
+	for start := 0; start < len(calls); start += rtorrentMulticallBatchSize {
+		end := start + rtorrentMulticallBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+
+		r, err := c.client.Multicall(calls[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, r...)
+	}
+
+	return results, nil
+}
+
+/* Interface  */
+
+func (c *RTorrent) Type() string {
+	return c.clientType
+}
+
+func (c *RTorrent) Connect() error {
+	// retrieve client version
+	v, err := c.client.Call("system.client_version")
+	if err != nil {
+		return fmt.Errorf("get client version: %w", err)
+	}
+
+	c.log.Debugf("Daemon Version: %v", rtorrentString(v))
+	return nil
+}
+
+func (c *RTorrent) LoadLabelPathMap() error {
+	// labels are not associated with paths in rtorrent
+	return nil
+}
+
+func (c *RTorrent) LabelPathMap() map[string]string {
+	return nil
+}
+
+func (c *RTorrent) GetTorrents() (map[string]config.Torrent, error) {
+	// retrieve torrents from client
+	c.log.Tracef("Retrieving torrents...")
+	v, err := c.client.Call("d.multicall2", append([]interface{}{"", "main"}, rtorrentTorrentFields...)...)
+	if err != nil {
+		return nil, fmt.Errorf("get torrents: %w", err)
+	}
+
+	rows, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("get torrents: unexpected response: %T", v)
+	}
+	c.log.Tracef("Retrieved %d torrents", len(rows))
+
+	// retrieve files and trackers of all torrents
+	calls := make([][]interface{}, 0, len(rows)*2)
+	for _, r := range rows {
+		row, ok := r.([]interface{})
+		if !ok || len(row) != len(rtorrentTorrentFields) {
+			return nil, fmt.Errorf("get torrents: unexpected torrent row: %#v", r)
+		}
+
+		hash := rtorrentString(row[0])
+		calls = append(calls,
+			[]interface{}{"f.multicall", hash, "", "f.path="},
+			[]interface{}{"t.multicall", hash, "", "t.url=", "t.is_enabled=", "t.scrape_complete=",
+				"t.scrape_incomplete=", "t.success_time_last=", "t.failed_time_last="},
+		)
+	}
+
+	details, err := c.multicall(calls)
+	if err != nil {
+		return nil, fmt.Errorf("get torrent files and trackers: %w", err)
+	}
+	c.log.Tracef("Retrieved files and trackers for %d torrents", len(rows))
+
+	// build torrent list
+	torrents := make(map[string]config.Torrent)
+	now := time.Now()
+
+	for i, r := range rows {
+		row := r.([]interface{})
+
+		hash := strings.ToUpper(rtorrentString(row[0]))
+		directory := rtorrentString(row[2])
+		multiFile := rtorrentInt(row[3]) != 0
+		complete := rtorrentInt(row[6])
+
+		// files
+		if f, ok := details[i*2].(*xmlrpc.Fault); ok {
+			return nil, fmt.Errorf("get torrent files: %v: %w", hash, f)
+		}
+
+		var files []string
+		if fileRows, ok := details[i*2].([]interface{}); ok {
+			for _, fr := range fileRows {
+				if f, ok := fr.([]interface{}); ok && len(f) > 0 {
+					files = append(files, filepath.Join(directory, rtorrentString(f[0])))
+				}
+			}
+		}
+
+		// trackers
+		if f, ok := details[i*2+1].(*xmlrpc.Fault); ok {
+			return nil, fmt.Errorf("get torrent trackers: %v: %w", hash, f)
+		}
+
+		trackerName := ""
+		trackerStatus := ""
+		var seeds int64 = 0
+		var peers int64 = 0
+
+		if trackerRows, ok := details[i*2+1].([]interface{}); ok {
+			for _, tr := range trackerRows {
+				t, ok := tr.([]interface{})
+				if !ok || len(t) < 6 {
+					continue
+				}
+
+				// skip disabled trackers and dht
+				trackerUrl := rtorrentString(t[0])
+				if rtorrentInt(t[1]) == 0 || strings.HasPrefix(trackerUrl, "dht://") {
+					continue
+				}
+
+				// use status of first enabled tracker
+				if trackerName == "" {
+					trackerName = parseTrackerDomain(trackerUrl)
+					trackerStatus = rtorrentTrackerStatus(rtorrentInt(t[4]), rtorrentInt(t[5]),
+						rtorrentString(row[16]))
+				}
+
+				if s := rtorrentInt(t[2]); s > seeds {
+					seeds = s
+				}
+				if p := rtorrentInt(t[3]); p > peers {
+					peers = p
+				}
+			}
+		}
+
+		// save path (directory of multi file torrents is the torrent folder itself)
+		savePath := directory
+		if multiFile {
+			savePath = filepath.Dir(directory)
+		}
+
+		// added time (prefer the rutorrent addtime)
+		addedTime := rtorrentInt(row[12])
+		if addedTime == 0 {
+			addedTime = rtorrentInt(row[14])
+		}
+
+		var addedTimeSecs int64 = 0
+		if addedTime > 0 {
+			addedTimeSecs = int64(now.Sub(time.Unix(addedTime, 0)).Seconds())
+		}
+
+		// seeding time (prefer the rutorrent seedingtime)
+		seedingStart := rtorrentInt(row[13])
+		if seedingStart == 0 {
+			seedingStart = rtorrentInt(row[15])
+		}
+
+		var seedingTimeSecs int64 = 0
+		if complete != 0 && seedingStart > 0 {
+			seedingTimeSecs = int64(now.Sub(time.Unix(seedingStart, 0)).Seconds())
+		}
+
+		state := rtorrentState(rtorrentInt(row[7]), rtorrentInt(row[8]), rtorrentInt(row[9]), complete)
+
+		// create torrent
+		torrent := config.Torrent{
+			Hash:            hash,
+			Name:            rtorrentString(row[1]),
+			Path:            savePath,
+			TotalBytes:      rtorrentInt(row[4]),
+			DownloadedBytes: rtorrentInt(row[5]),
+			UploadedBytes:   rtorrentInt(row[17]),
+			State:           state,
+			Files:           files,
+			Tags:            []string{},
+			Downloaded:      complete != 0,
+			Seeding:         state == "seeding",
+			Ratio:           float32(rtorrentInt(row[10])) / 1000,
+			AddedSeconds:    addedTimeSecs,
+			AddedHours:      float32(addedTimeSecs) / 60 / 60,
+			AddedDays:       float32(addedTimeSecs) / 60 / 60 / 24,
+			SeedingSeconds:  seedingTimeSecs,
+			SeedingHours:    float32(seedingTimeSecs) / 60 / 60,
+			SeedingDays:     float32(seedingTimeSecs) / 60 / 60 / 24,
+			Label:           rtorrentLabel(rtorrentString(row[11])),
+			Seeds:           seeds,
+			Peers:           peers,
+			// free space
+			FreeSpaceGB:  c.GetFreeSpace,
+			FreeSpaceSet: c.freeSpaceSet,
+			// tracker
+			TrackerName:   trackerName,
+			TrackerStatus: trackerStatus,
+		}
+
+		torrents[hash] = torrent
+	}
+
+	return torrents, nil
+}
+
+func (c *RTorrent) RemoveTorrent(hash string, deleteData bool) (bool, error) {
+	// retrieve data paths before the torrent is erased
+	var files, dirs []string
+	if deleteData {
+		var err error
+		if files, dirs, err = c.getTorrentDataPaths(hash); err != nil {
+			return false, fmt.Errorf("get torrent data paths: %v: %w", hash, err)
+		}
+	}
+
+	// pause torrent
+	if _, err := c.client.Call("d.stop", hash); err != nil {
+		return false, fmt.Errorf("pause torrent: %v: %w", hash, err)
+	}
+
+	time.Sleep(1 * time.Second)
+
+	// resume torrent
+	if _, err := c.client.Call("d.start", hash); err != nil {
+		return false, fmt.Errorf("resume torrent: %v: %w", hash, err)
+	}
+
+	// sleep before re-announcing torrent
+	time.Sleep(2 * time.Second)
+
+	if _, err := c.client.Call("d.tracker_announce", hash); err != nil {
+		return false, fmt.Errorf("re-announce torrent: %v: %w", hash, err)
+	}
+
+	// sleep before removing torrent
+	time.Sleep(2 * time.Second)
+
+	// remove
+	if _, err := c.client.Call("d.erase", hash); err != nil {
+		return false, fmt.Errorf("remove torrent: %v: %w", hash, err)
+	}
+
+	// rtorrent does not remove data when erasing, so remove the files of the torrent on the rtorrent host
+	for start := 0; start < len(files); start += rtorrentRemoveBatchSize {
+		end := min(start+rtorrentRemoveBatchSize, len(files))
+
+		args := append([]interface{}{"", "rm", "-f", "--"}, toInterfaces(files[start:end])...)
+		if _, err := c.client.Call("execute.throw", args...); err != nil {
+			return false, fmt.Errorf("remove torrent files: %v: %w", hash, err)
+		}
+	}
+
+	// then prune the folders of the torrent left empty, folders still containing other files fail to be removed
+	if len(dirs) > 0 {
+		args := append([]interface{}{"", "rmdir", "--"}, toInterfaces(dirs)...)
+		if _, err := c.client.Call("execute.nothrow", args...); err != nil {
+			return false, fmt.Errorf("remove torrent folders: %v: %w", hash, err)
+		}
+	}
+
+	return true, nil
+}
+
+func (c *RTorrent) SetTorrentLabel(hash string, label string, hardlink bool) error {
+	// hardlink behaviour currently not supported for rtorrent
+	if hardlink {
+		return errors.New("hardlink relabeling not supported for rtorrent (yet)")
+	}
+
+	// set label (url-encoded for rutorrent)
+	if _, err := c.client.Call("d.custom1.set", hash, url.PathEscape(label)); err != nil {
+		return fmt.Errorf("set torrent label: %v: %w", label, err)
+	}
+
+	return nil
+}
+
+func (c *RTorrent) GetCurrentFreeSpace(path string) (int64, error) {
+	// rtorrent can only report free space for the location of a torrent,
+	// so find a torrent stored within path
+	v, err := c.client.Call("d.multicall2", "", "main", "d.hash=", "d.directory=")
+	if err != nil {
+		return 0, fmt.Errorf("get torrents: %w", err)
+	}
+
+	rows, _ := v.([]interface{})
+	hash := ""
+	cleanPath := filepath.Clean(path)
+
+	for _, r := range rows {
+		row, ok := r.([]interface{})
+		if !ok || len(row) < 2 {
+			continue
+		}
+
+		dir := filepath.Clean(rtorrentString(row[1]))
+		if dir == cleanPath || strings.HasPrefix(dir, cleanPath+string(filepath.Separator)) {
+			hash = rtorrentString(row[0])
+			break
+		}
+	}
+
+	if hash == "" {
+		return 0, fmt.Errorf("get free disk space: %v: no torrent found within path", path)
+	}
+
+	// get free disk space
+	v, err = c.client.Call("d.free_diskspace", hash)
+	if err != nil {
+		return 0, fmt.Errorf("get free disk space: %v: %w", path, err)
+	}
+
+	space := rtorrentInt(v)
+
+	// set internal free size
+	c.freeSpaceGB = float64(space) / humanize.GiByte
+	c.freeSpaceSet = true
+
+	return space, nil
+}
+
+func (c *RTorrent) AddFreeSpace(bytes int64) {
+	c.freeSpaceGB += float64(bytes) / humanize.GiByte
+}
+
+func (c *RTorrent) GetFreeSpace() float64 {
+	return c.freeSpaceGB
+}
+
+/* Filters */
+
+func (c *RTorrent) ShouldIgnore(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Ignores)
+	if err != nil {
+		return true, nil, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("ignore"), nil
+}
+
+func (c *RTorrent) ShouldRemove(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Removes)
+	if err != nil {
+		return false, nil, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("remove"), nil
+}
+
+func (c *RTorrent) ShouldRelabel(t *config.Torrent) (string, bool, *expression.Match, error) {
+	for _, label := range c.exp.Labels {
+		// check update
+		match, err := expression.CheckTorrentAllMatch(t, label.Updates)
+		if err != nil {
+			return "", false, nil, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+		} else if !match {
+			continue
+		}
+
+		// we should re-label
+		return label.Name, true, label.Match(), nil
+	}
+
+	return "", false, nil, nil
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/xmlrpc"
+)
+
+func TestRTorrentTrackerStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		successTime int64
+		failedTime  int64
+		message     string
+		want        string
+	}{
+		{"never announced", 0, 0, "", ""},
+		{"working", 200, 0, "", ""},
+		{"failed before latest success", 200, 100, `Tracker: [Failure reason "Unregistered torrent"]`, ""},
+		{"stale storage message", 200, 0, "Storage error: [File not found]", ""},
+		{"unregistered", 100, 200, `Tracker: [Failure reason "Unregistered torrent"]`, `Failure reason "Unregistered torrent"`},
+		{"failed without reason", 100, 200, "", "Tracker announce failed"},
+		{"failed with other message", 0, 200, "Storage error: [File not found]", "Tracker announce failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rtorrentTrackerStatus(tt.successTime, tt.failedTime, tt.message); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+/* Fake */
+
+// fakeRTorrent is an rtorrent xml-rpc server holding a single torrent, recording the calls made.
+type fakeRTorrent struct {
+	mu        sync.Mutex
+	directory string
+	multiFile bool
+	files     []string
+	calls     [][]interface{}
+}
+
+func (f *fakeRTorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	method, params, err := decodeCall(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.calls = append(f.calls, append([]interface{}{method}, params...))
+
+	var result interface{} = 0
+	if method == "system.multicall" {
+		rows := make([]interface{}, 0, len(f.files))
+		for _, file := range f.files {
+			rows = append(rows, []interface{}{file})
+		}
+
+		multiFile := 0
+		if f.multiFile {
+			multiFile = 1
+		}
+
+		// d.directory, d.is_multi_file and f.multicall
+		result = []interface{}{[]interface{}{f.directory}, []interface{}{multiFile}, []interface{}{rows}}
+	}
+
+	b, err := xmlrpc.EncodeCall("", result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// a call encodes its value the same as a response, but for its root element
+	resp := strings.Replace(string(b), "<methodCall><methodName></methodName>", "<methodResponse>", 1)
+	_, _ = w.Write([]byte(strings.Replace(resp, "</methodCall>", "</methodResponse>", 1)))
+}
+
+// executed returns the arguments of the calls made to an execute method.
+func (f *fakeRTorrent) executed(method string) [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	executed := make([][]interface{}, 0)
+	for _, c := range f.calls {
+		if c[0] == method {
+			executed = append(executed, c[1:])
+		}
+	}
+
+	return executed
+}
+
+// decodeCall decodes the method and params of an xml-rpc call.
+func decodeCall(r io.Reader) (string, []interface{}, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var call struct {
+		Method string `xml:"methodName"`
+	}
+	if err := xml.Unmarshal(b, &call); err != nil {
+		return "", nil, err
+	}
+
+	// decode the params as a single array value
+	body := strings.NewReplacer("<param>", "", "</param>", "",
+		"<params>", "<params><param><value><array><data>",
+		"</params>", "</data></array></value></param></params>").Replace(string(b))
+	v, err := xmlrpc.DecodeResponse(strings.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+
+	params, _ := v.([]interface{})
+	return call.Method, params, nil
+}
+
+func newTestRTorrent(t *testing.T, f *fakeRTorrent) *RTorrent {
+	t.Helper()
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return &RTorrent{log: logger.GetLogger("rtorrent"), client: xmlrpc.NewClient(srv.URL, "", "", time.Second)}
+}
+
+/* Tests */
+
+func TestRTorrentRemoveTorrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		torrent    *fakeRTorrent
+		deleteData bool
+		wantRm     [][]interface{}
+		wantRmdir  [][]interface{}
+	}{
+		{
+			name:       "multi file",
+			torrent:    &fakeRTorrent{directory: "/downloads/Some.Torrent", multiFile: true, files: []string{"file.mkv", "Sub/file.nfo"}},
+			deleteData: true,
+			wantRm: [][]interface{}{{"", "rm", "-f", "--",
+				"/downloads/Some.Torrent/file.mkv", "/downloads/Some.Torrent/Sub/file.nfo"}},
+			// only the folders of the torrent, deepest first
+			wantRmdir: [][]interface{}{{"", "rmdir", "--", "/downloads/Some.Torrent/Sub", "/downloads/Some.Torrent"}},
+		},
+		{
+			name:       "single file",
+			torrent:    &fakeRTorrent{directory: "/downloads", files: []string{"Some.Movie.mkv"}},
+			deleteData: true,
+			wantRm:     [][]interface{}{{"", "rm", "-f", "--", "/downloads/Some.Movie.mkv"}},
+			// the directory of single file torrents is shared with other torrents
+			wantRmdir: [][]interface{}{},
+		},
+		{
+			name:       "without data",
+			torrent:    &fakeRTorrent{directory: "/downloads/Some.Torrent", multiFile: true, files: []string{"file.mkv"}},
+			deleteData: false,
+			wantRm:     [][]interface{}{},
+			wantRmdir:  [][]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// removals wait between their calls
+			t.Parallel()
+
+			f := tt.torrent
+			c := newTestRTorrent(t, f)
+
+			removed, err := c.RemoveTorrent("HASH", tt.deleteData)
+			if err != nil {
+				t.Fatalf("remove torrent: %v", err)
+			} else if !removed {
+				t.Fatal("expected torrent to be removed")
+			}
+
+			if got := f.executed("execute.throw"); !reflect.DeepEqual(got, tt.wantRm) {
+				t.Errorf("expected files removed with %v, got %v", tt.wantRm, got)
+			}
+			if got := f.executed("execute.nothrow"); !reflect.DeepEqual(got, tt.wantRmdir) {
+				t.Errorf("expected folders removed with %v, got %v", tt.wantRmdir, got)
+			}
+			if got := f.executed("d.erase"); !reflect.DeepEqual(got, [][]interface{}{{"HASH"}}) {
+				t.Errorf("expected torrent to be erased, got %v", got)
+			}
+			if !tt.deleteData && len(f.executed("system.multicall")) != 0 {
+				t.Error("expected data paths not to be retrieved without deleting data")
+			}
+		})
+	}
+}
+
+func TestRTorrentRemoveTorrentOutsideDirectory(t *testing.T) {
+	f := &fakeRTorrent{directory: "/downloads/Some.Torrent", multiFile: true, files: []string{"../Other.Torrent/file.mkv"}}
+	c := newTestRTorrent(t, f)
+
+	if _, err := c.RemoveTorrent("HASH", true); err == nil {
+		t.Fatal("expected an error for a file outside of the torrent directory")
+	}
+
+	// nothing is done before the data paths are known
+	if len(f.executed("d.erase")) != 0 || len(f.executed("execute.throw")) != 0 {
+		t.Errorf("expected torrent to be kept, got calls %v", f.calls)
+	}
+}
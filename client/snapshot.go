@@ -0,0 +1,292 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/sliceutils"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+/* Struct */
+
+// SnapshotFile holds the state of a client captured by the snapshot command.
+type SnapshotFile struct {
+	Client  string    `json:"Client"`
+	Type    string    `json:"Type"`
+	Created time.Time `json:"Created"`
+	// free space in bytes of the free_space_path of the client, when configured
+	FreeSpace       *int64                    `json:"FreeSpace,omitempty"`
+	LabelPathMap    map[string]string         `json:"LabelPathMap,omitempty"`
+	DefaultSavePath string                    `json:"DefaultSavePath,omitempty"`
+	Torrents        map[string]config.Torrent `json:"Torrents"`
+}
+
+// Snapshot is an offline client serving the torrents of a snapshot file, changes are only kept in memory.
+type Snapshot struct {
+	Path *string `validate:"required"`
+
+	// internal
+	log        *logrus.Entry
+	clientType string
+	snapshot   *SnapshotFile
+
+	// set by cmd handler
+	freeSpaceGB  float64
+	freeSpaceSet bool
+
+	// internal compiled filters
+	exp *expression.Expressions
+}
+
+/* Initializer */
+
+func NewSnapshot(name string, exp *expression.Expressions) (TagInterface, error) {
+	tc := Snapshot{
+		log:        logger.GetLogger(name),
+		clientType: "Snapshot",
+		exp:        exp,
+	}
+
+	// load config
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s", config.Delimiter, name), &tc); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	// validate config
+	if errs := config.ValidateStruct(tc); errs != nil {
+		return nil, fmt.Errorf("validate config: %v", errs)
+	}
+
+	return &tc, nil
+}
+
+/* Public */
+
+// LoadSnapshotFile loads a snapshot written by the snapshot command.
+func LoadSnapshotFile(path string) (*SnapshotFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	s := new(SnapshotFile)
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if s.Torrents == nil {
+		s.Torrents = make(map[string]config.Torrent)
+	}
+
+	return s, nil
+}
+
+/* Interface */
+
+func (c *Snapshot) Type() string {
+	return c.clientType
+}
+
+func (c *Snapshot) Connect() error {
+	s, err := LoadSnapshotFile(*c.Path)
+	if err != nil {
+		return fmt.Errorf("load snapshot: %q: %w", *c.Path, err)
+	}
+
+	c.snapshot = s
+	c.log.Debugf("Loaded snapshot of %q (%s) with %d torrents, created: %s", s.Client, s.Type,
+		len(s.Torrents), s.Created.Format(time.RFC3339))
+	return nil
+}
+
+func (c *Snapshot) LoadLabelPathMap() error {
+	// label paths are captured in the snapshot
+	return nil
+}
+
+func (c *Snapshot) LabelPathMap() map[string]string {
+	return c.snapshot.LabelPathMap
+}
+
+func (c *Snapshot) DefaultSavePath() string {
+	return c.snapshot.DefaultSavePath
+}
+
+func (c *Snapshot) GetTorrents() (map[string]config.Torrent, error) {
+	torrents := make(map[string]config.Torrent, len(c.snapshot.Torrents))
+	for h, t := range c.snapshot.Torrents {
+		t.Tags = append([]string(nil), t.Tags...)
+		t.FreeSpaceGB = c.GetFreeSpace
+		t.FreeSpaceSet = c.freeSpaceSet
+
+		torrents[h] = t
+	}
+
+	return torrents, nil
+}
+
+func (c *Snapshot) RemoveTorrent(hash string, deleteData bool) (bool, error) {
+	if _, ok := c.snapshot.Torrents[hash]; !ok {
+		return false, fmt.Errorf("torrent not found in snapshot: %v", hash)
+	}
+
+	delete(c.snapshot.Torrents, hash)
+	return true, nil
+}
+
+func (c *Snapshot) SetTorrentLabel(hash string, label string, hardlink bool) error {
+	t, ok := c.snapshot.Torrents[hash]
+	if !ok {
+		return fmt.Errorf("torrent not found in snapshot: %v", hash)
+	}
+
+	t.Label = label
+	c.snapshot.Torrents[hash] = t
+	return nil
+}
+
+func (c *Snapshot) GetCurrentFreeSpace(path string) (int64, error) {
+	if c.snapshot.FreeSpace == nil {
+		return 0, errors.New("snapshot does not contain free space")
+	}
+
+	// set internal free size
+	c.freeSpaceGB = float64(*c.snapshot.FreeSpace) / humanize.GiByte
+	c.freeSpaceSet = true
+
+	return *c.snapshot.FreeSpace, nil
+}
+
+func (c *Snapshot) AddFreeSpace(bytes int64) {
+	c.freeSpaceGB += float64(bytes) / humanize.GiByte
+}
+
+func (c *Snapshot) GetFreeSpace() float64 {
+	return c.freeSpaceGB
+}
+
+/* Filters */
+
+func (c *Snapshot) ShouldIgnore(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Ignores)
+	if err != nil {
+		return true, nil, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("ignore"), nil
+}
+
+func (c *Snapshot) ShouldRemove(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Removes)
+	if err != nil {
+		return false, nil, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("remove"), nil
+}
+
+func (c *Snapshot) ShouldRelabel(t *config.Torrent) (string, bool, *expression.Match, error) {
+	for _, label := range c.exp.Labels {
+		// check update
+		match, err := expression.CheckTorrentAllMatch(t, label.Updates)
+		if err != nil {
+			return "", false, nil, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+		} else if !match {
+			continue
+		}
+
+		// we should re-label
+		return label.Name, true, label.Match(), nil
+	}
+
+	return "", false, nil, nil
+}
+
+func (c *Snapshot) ShouldRetag(t *config.Torrent) (RetagInfo, bool, error) {
+	var retagInfo = RetagInfo{}
+
+	for _, tag := range c.exp.Tags {
+		// check update
+		match, err := expression.CheckTorrentAllMatch(t, tag.Updates)
+		if err != nil {
+			return RetagInfo{}, false, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+		}
+
+		var containTag = sliceutils.StringSliceContains(t.Tags, tag.Name, false)
+		var tagMode = tag.Mode
+
+		if containTag && !match && (tagMode == "remove" || tagMode == "full") {
+			// we should remove the tag
+			retagInfo.Remove = append(retagInfo.Remove, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
+		}
+		if !containTag && match && (tagMode == "add" || tagMode == "full") {
+			// we should add the tag
+			retagInfo.Add = append(retagInfo.Add, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
+		}
+	}
+
+	return retagInfo, len(retagInfo.Add) != 0 || len(retagInfo.Remove) != 0, nil
+}
+
+func (c *Snapshot) AddTags(hash string, tags []string) error {
+	t, ok := c.snapshot.Torrents[hash]
+	if !ok {
+		return fmt.Errorf("torrent not found in snapshot: %v", hash)
+	}
+
+	for _, tag := range tags {
+		if !sliceutils.StringSliceContains(t.Tags, tag, false) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+
+	c.snapshot.Torrents[hash] = t
+	return nil
+}
+
+func (c *Snapshot) RemoveTags(hash string, tags []string) error {
+	t, ok := c.snapshot.Torrents[hash]
+	if !ok {
+		return fmt.Errorf("torrent not found in snapshot: %v", hash)
+	}
+
+	t.Tags = removeSliceItems(t.Tags, tags)
+	c.snapshot.Torrents[hash] = t
+	return nil
+}
+
+func (c *Snapshot) CreateTags(tags []string) error {
+	return nil
+}
+
+func (c *Snapshot) DeleteTags(tags []string) error {
+	return nil
+}
+
+/* Private */
+
+func removeSliceItems(slice []string, remove []string) []string {
+	result := make([]string, 0, len(slice))
+	for _, v := range slice {
+		if !sliceutils.StringSliceContains(remove, v, false) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -2,11 +2,14 @@ package client
 
 import (
 	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
 )
 
 type RetagInfo struct {
 	Add    []string
 	Remove []string
+	// tag expressions responsible for the added and removed tags
+	Matches []*expression.Match
 }
 
 type TagInterface interface {
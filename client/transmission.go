@@ -0,0 +1,618 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/sliceutils"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+/* Const */
+
+const (
+	transmissionSessionIdHeader = "X-Transmission-Session-Id"
+)
+
+/* Vars */
+
+var (
+	transmissionStatuses = map[int]string{
+		0: "stopped",
+		1: "checkWait",
+		2: "checking",
+		3: "downloadWait",
+		4: "downloading",
+		5: "seedWait",
+		6: "seeding",
+	}
+
+	transmissionTorrentFields = []string{
+		"hashString",
+		"name",
+		"downloadDir",
+		"totalSize",
+		"downloadedEver",
+		"uploadedEver",
+		"status",
+		"percentDone",
+		"uploadRatio",
+		"addedDate",
+		"secondsSeeding",
+		"labels",
+		"files",
+		"trackerStats",
+	}
+)
+
+/* Struct */
+
+type Transmission struct {
+	Url      *string `validate:"required"`
+	User     string
+	Password string
+
+	// internal
+	log        *logrus.Entry
+	clientType string
+	http       *http.Client
+	rpcUrl     string
+	sessionId  string
+
+	// set by cmd handler
+	freeSpaceGB  float64
+	freeSpaceSet bool
+
+	// internal compiled filters
+	exp *expression.Expressions
+}
+
+type transmissionRequest struct {
+	Method    string      `json:"method"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+type transmissionResponse struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type transmissionTorrent struct {
+	HashString     string   `json:"hashString"`
+	Name           string   `json:"name"`
+	DownloadDir    string   `json:"downloadDir"`
+	TotalSize      int64    `json:"totalSize"`
+	DownloadedEver int64    `json:"downloadedEver"`
+	UploadedEver   int64    `json:"uploadedEver"`
+	Status         int      `json:"status"`
+	PercentDone    float64  `json:"percentDone"`
+	UploadRatio    float64  `json:"uploadRatio"`
+	AddedDate      int64    `json:"addedDate"`
+	SecondsSeeding int64    `json:"secondsSeeding"`
+	Labels         []string `json:"labels"`
+	Files          []struct {
+		Name string `json:"name"`
+	} `json:"files"`
+	TrackerStats []struct {
+		Announce           string `json:"announce"`
+		Host               string `json:"host"`
+		Tier               int    `json:"tier"`
+		LastAnnounceResult string `json:"lastAnnounceResult"`
+		SeederCount        int64  `json:"seederCount"`
+		LeecherCount       int64  `json:"leecherCount"`
+	} `json:"trackerStats"`
+}
+
+/* Initializer */
+
+func NewTransmission(name string, exp *expression.Expressions) (TagInterface, error) {
+	tc := Transmission{
+		log:        logger.GetLogger(name),
+		clientType: "Transmission",
+		exp:        exp,
+	}
+
+	// load config
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s", config.Delimiter, name), &tc); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	// validate config
+	if errs := config.ValidateStruct(tc); errs != nil {
+		return nil, fmt.Errorf("validate config: %v", errs)
+	}
+
+	// determine rpc url
+	tc.rpcUrl = strings.TrimSuffix(*tc.Url, "/")
+	if !strings.HasSuffix(tc.rpcUrl, "/rpc") {
+		tc.rpcUrl += "/transmission/rpc"
+	}
+
+	// init client
+	tc.http = &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	return &tc, nil
+}
+
+/* Private */
+
+func (c *Transmission) call(method string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(transmissionRequest{
+		Method:    method,
+		Arguments: args,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	// transmission rejects the first request of a session with 409 and hands out the session id,
+	// so allow a single retry using the session id returned
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, c.rpcUrl, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if c.User != "" || c.Password != "" {
+			req.SetBasicAuth(c.User, c.Password)
+		}
+
+		if c.sessionId != "" {
+			req.Header.Set(transmissionSessionIdHeader, c.sessionId)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("request %v: %w", method, err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusConflict:
+			resp.Body.Close()
+
+			c.sessionId = resp.Header.Get(transmissionSessionIdHeader)
+			c.log.Tracef("Retrieved new session id: %s", c.sessionId)
+			continue
+		case http.StatusOK:
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("request %v: unexpected response: %s", method, resp.Status)
+		}
+
+		// decode response
+		r := new(transmissionResponse)
+		err = json.NewDecoder(resp.Body).Decode(r)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decode %v response: %w", method, err)
+		}
+
+		if r.Result != "success" {
+			return fmt.Errorf("request %v: %v", method, r.Result)
+		}
+
+		if result != nil && len(r.Arguments) > 0 {
+			if err := json.Unmarshal(r.Arguments, result); err != nil {
+				return fmt.Errorf("decode %v arguments: %w", method, err)
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("request %v: failed negotiating session id", method)
+}
+
+func (c *Transmission) getTorrent(hash string, fields ...string) (*transmissionTorrent, error) {
+	var resp struct {
+		Torrents []transmissionTorrent `json:"torrents"`
+	}
+
+	if err := c.call("torrent-get", map[string]interface{}{
+		"ids":    []string{hash},
+		"fields": fields,
+	}, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Torrents) == 0 {
+		return nil, fmt.Errorf("torrent not found: %v", hash)
+	}
+
+	return &resp.Torrents[0], nil
+}
+
+func (c *Transmission) setLabels(hash string, labels []string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+
+	return c.call("torrent-set", map[string]interface{}{
+		"ids":    []string{hash},
+		"labels": labels,
+	}, nil)
+}
+
+/* Interface  */
+
+func (c *Transmission) Type() string {
+	return c.clientType
+}
+
+func (c *Transmission) Connect() error {
+	var session struct {
+		Version           string `json:"version"`
+		RpcVersion        int    `json:"rpc-version"`
+		RpcVersionMinimum int    `json:"rpc-version-minimum"`
+	}
+
+	// retrieve & validate session
+	if err := c.call("session-get", map[string]interface{}{
+		"fields": []string{"version", "rpc-version", "rpc-version-minimum"},
+	}, &session); err != nil {
+		return fmt.Errorf("get session: %w", err)
+	} else if session.RpcVersion < 16 {
+		// labels were added in rpc version 16 (transmission 3.00)
+		return fmt.Errorf("unsupported rpc version: %v", session.RpcVersion)
+	}
+
+	c.log.Debugf("Daemon Version: %v (rpc: %d)", session.Version, session.RpcVersion)
+	return nil
+}
+
+func (c *Transmission) LoadLabelPathMap() error {
+	// labels are not associated with paths in transmission
+	return nil
+}
+
+func (c *Transmission) LabelPathMap() map[string]string {
+	return nil
+}
+
+func (c *Transmission) GetTorrents() (map[string]config.Torrent, error) {
+	// retrieve torrents from client
+	c.log.Tracef("Retrieving torrents...")
+	var resp struct {
+		Torrents []transmissionTorrent `json:"torrents"`
+	}
+
+	if err := c.call("torrent-get", map[string]interface{}{
+		"fields": transmissionTorrentFields,
+	}, &resp); err != nil {
+		return nil, fmt.Errorf("get torrents: %w", err)
+	}
+	c.log.Tracef("Retrieved %d torrents", len(resp.Torrents))
+
+	// build torrent list
+	torrents := make(map[string]config.Torrent)
+	for _, t := range resp.Torrents {
+		t := t
+
+		// parse tracker details
+		trackerName := ""
+		trackerStatus := ""
+		trackerTier := -1
+		var seeds int64 = 0
+		var peers int64 = 0
+
+		for _, tracker := range t.TrackerStats {
+			// use status of first tracker in the lowest tier
+			if trackerTier == -1 || tracker.Tier < trackerTier {
+				trackerName = parseTrackerDomain(tracker.Announce)
+				trackerStatus = tracker.LastAnnounceResult
+				trackerTier = tracker.Tier
+			}
+
+			// tracker counts are -1 when unknown
+			if tracker.SeederCount > seeds {
+				seeds = tracker.SeederCount
+			}
+			if tracker.LeecherCount > peers {
+				peers = tracker.LeecherCount
+			}
+		}
+
+		// added time
+		addedTimeSecs := int64(time.Since(time.Unix(t.AddedDate, 0)).Seconds())
+
+		// torrent files
+		var files []string
+		for _, f := range t.Files {
+			files = append(files, filepath.Join(t.DownloadDir, f.Name))
+		}
+
+		// labels are the tags of the torrent, the first label is treated as the torrent label as well
+		label := ""
+		tags := append([]string{}, t.Labels...)
+		if len(t.Labels) > 0 {
+			label = t.Labels[0]
+		}
+
+		state := transmissionStatuses[t.Status]
+
+		// create torrent
+		torrent := config.Torrent{
+			Hash:            t.HashString,
+			Name:            t.Name,
+			Path:            t.DownloadDir,
+			TotalBytes:      t.TotalSize,
+			DownloadedBytes: t.DownloadedEver,
+			UploadedBytes:   t.UploadedEver,
+			State:           state,
+			Files:           files,
+			Tags:            tags,
+			Downloaded:      t.PercentDone >= 1.0,
+			Seeding:         state == "seeding",
+			Ratio:           float32(t.UploadRatio),
+			AddedSeconds:    addedTimeSecs,
+			AddedHours:      float32(addedTimeSecs) / 60 / 60,
+			AddedDays:       float32(addedTimeSecs) / 60 / 60 / 24,
+			SeedingSeconds:  t.SecondsSeeding,
+			SeedingHours:    float32(t.SecondsSeeding) / 60 / 60,
+			SeedingDays:     float32(t.SecondsSeeding) / 60 / 60 / 24,
+			Label:           label,
+			Seeds:           seeds,
+			Peers:           peers,
+			// free space
+			FreeSpaceGB:  c.GetFreeSpace,
+			FreeSpaceSet: c.freeSpaceSet,
+			// tracker
+			TrackerName:   trackerName,
+			TrackerStatus: trackerStatus,
+		}
+
+		torrents[t.HashString] = torrent
+	}
+
+	return torrents, nil
+}
+
+func (c *Transmission) RemoveTorrent(hash string, deleteData bool) (bool, error) {
+	ids := map[string]interface{}{
+		"ids": []string{hash},
+	}
+
+	// pause torrent
+	if err := c.call("torrent-stop", ids, nil); err != nil {
+		return false, fmt.Errorf("pause torrent: %v: %w", hash, err)
+	}
+
+	time.Sleep(1 * time.Second)
+
+	// resume torrent
+	if err := c.call("torrent-start", ids, nil); err != nil {
+		return false, fmt.Errorf("resume torrent: %v: %w", hash, err)
+	}
+
+	// sleep before re-announcing torrent
+	time.Sleep(2 * time.Second)
+
+	if err := c.call("torrent-reannounce", ids, nil); err != nil {
+		return false, fmt.Errorf("re-announce torrent: %v: %w", hash, err)
+	}
+
+	// sleep before removing torrent
+	time.Sleep(2 * time.Second)
+
+	// remove
+	if err := c.call("torrent-remove", map[string]interface{}{
+		"ids":               []string{hash},
+		"delete-local-data": deleteData,
+	}, nil); err != nil {
+		return false, fmt.Errorf("remove torrent: %v: %w", hash, err)
+	}
+
+	return true, nil
+}
+
+func (c *Transmission) SetTorrentLabel(hash string, label string, hardlink bool) error {
+	// hardlink behaviour currently not supported for transmission
+	if hardlink {
+		return errors.New("hardlink relabeling not supported for transmission (yet)")
+	}
+
+	t, err := c.getTorrent(hash, "labels")
+	if err != nil {
+		return fmt.Errorf("get torrent labels: %w", err)
+	}
+
+	// replace the first label, keeping any others
+	labels := []string{label}
+	for i, l := range t.Labels {
+		if i == 0 || l == label {
+			continue
+		}
+
+		labels = append(labels, l)
+	}
+
+	if label == "" {
+		if len(labels) > 1 {
+			// the first tag would become the label
+			return fmt.Errorf("remove label of torrent with tags: %v", hash)
+		}
+
+		labels = nil
+	}
+
+	// set label
+	if err := c.setLabels(hash, labels); err != nil {
+		return fmt.Errorf("set torrent label: %v: %w", label, err)
+	}
+
+	return nil
+}
+
+func (c *Transmission) GetCurrentFreeSpace(path string) (int64, error) {
+	var resp struct {
+		Path      string `json:"path"`
+		SizeBytes int64  `json:"size-bytes"`
+	}
+
+	// get free disk space
+	if err := c.call("free-space", map[string]interface{}{
+		"path": path,
+	}, &resp); err != nil {
+		return 0, fmt.Errorf("get free disk space: %v: %w", path, err)
+	}
+
+	// set internal free size
+	c.freeSpaceGB = float64(resp.SizeBytes) / humanize.GiByte
+	c.freeSpaceSet = true
+
+	return resp.SizeBytes, nil
+}
+
+func (c *Transmission) AddFreeSpace(bytes int64) {
+	c.freeSpaceGB += float64(bytes) / humanize.GiByte
+}
+
+func (c *Transmission) GetFreeSpace() float64 {
+	return c.freeSpaceGB
+}
+
+/* Filters */
+
+func (c *Transmission) ShouldIgnore(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Ignores)
+	if err != nil {
+		return true, nil, fmt.Errorf("check ignore expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("ignore"), nil
+}
+
+func (c *Transmission) ShouldRemove(t *config.Torrent) (bool, *expression.Match, error) {
+	match, err := expression.CheckTorrentSingleMatch(t, c.exp.Removes)
+	if err != nil {
+		return false, nil, fmt.Errorf("check remove expression: %v: %w", t.Hash, err)
+	} else if match == nil {
+		return false, nil, nil
+	}
+
+	return true, match.Match("remove"), nil
+}
+
+func (c *Transmission) ShouldRelabel(t *config.Torrent) (string, bool, *expression.Match, error) {
+	for _, label := range c.exp.Labels {
+		// check update
+		match, err := expression.CheckTorrentAllMatch(t, label.Updates)
+		if err != nil {
+			return "", false, nil, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+		} else if !match {
+			continue
+		}
+
+		// we should re-label
+		return label.Name, true, label.Match(), nil
+	}
+
+	return "", false, nil, nil
+}
+
+func (c *Transmission) ShouldRetag(t *config.Torrent) (RetagInfo, bool, error) {
+	var retagInfo = RetagInfo{}
+
+	for _, tag := range c.exp.Tags {
+		// check update
+		match, err := expression.CheckTorrentAllMatch(t, tag.Updates)
+		if err != nil {
+			return RetagInfo{}, false, fmt.Errorf("check update expression: %v: %w", t.Hash, err)
+		}
+
+		var containTag = sliceutils.StringSliceContains(t.Tags, tag.Name, false)
+		var tagMode = tag.Mode
+
+		if containTag && !match && (tagMode == "remove" || tagMode == "full") {
+			// we should remove the tag
+			retagInfo.Remove = append(retagInfo.Remove, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
+		}
+		if !containTag && match && (tagMode == "add" || tagMode == "full") {
+			// we should add the tag
+			retagInfo.Add = append(retagInfo.Add, tag.Name)
+			retagInfo.Matches = append(retagInfo.Matches, tag.Match())
+		}
+	}
+
+	return retagInfo, len(retagInfo.Add) != 0 || len(retagInfo.Remove) != 0, nil
+}
+
+func (c *Transmission) AddTags(hash string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	t, err := c.getTorrent(hash, "labels")
+	if err != nil {
+		return fmt.Errorf("get torrent labels: %w", err)
+	}
+
+	labels := t.Labels
+	for _, tag := range tags {
+		if !sliceutils.StringSliceContains(labels, tag, false) {
+			labels = append(labels, tag)
+		}
+	}
+
+	if err := c.setLabels(hash, labels); err != nil {
+		return fmt.Errorf("add torrent tags: %v: %w", tags, err)
+	}
+
+	return nil
+}
+
+func (c *Transmission) RemoveTags(hash string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	t, err := c.getTorrent(hash, "labels")
+	if err != nil {
+		return fmt.Errorf("get torrent labels: %w", err)
+	}
+
+	labels := make([]string, 0, len(t.Labels))
+	for _, l := range t.Labels {
+		if !sliceutils.StringSliceContains(tags, l, false) {
+			labels = append(labels, l)
+		}
+	}
+
+	if err := c.setLabels(hash, labels); err != nil {
+		return fmt.Errorf("remove torrent tags: %v: %w", tags, err)
+	}
+
+	return nil
+}
+
+func (c *Transmission) CreateTags(tags []string) error {
+	// labels do not need to be created in transmission
+	return nil
+}
+
+func (c *Transmission) DeleteTags(tags []string) error {
+	// labels do not need to be deleted in transmission
+	return nil
+}
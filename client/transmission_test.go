@@ -0,0 +1,274 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/autobrr/tqm/logger"
+)
+
+/* Fake */
+
+// fakeTransmission is a transmission rpc server holding a single torrent.
+type fakeTransmission struct {
+	mu        sync.Mutex
+	sessionId string
+	conflicts int
+	methods   []string
+	labels    []string
+	removed   map[string]interface{}
+}
+
+func (f *fakeTransmission) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// requests without the current session id are rejected with it
+	if r.Header.Get(transmissionSessionIdHeader) != f.sessionId {
+		f.conflicts++
+		w.Header().Set(transmissionSessionIdHeader, f.sessionId)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var req struct {
+		Method    string                 `json:"method"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.methods = append(f.methods, req.Method)
+
+	var args interface{}
+	switch req.Method {
+	case "session-get":
+		args = map[string]interface{}{"version": "4.0.5", "rpc-version": 17, "rpc-version-minimum": 14}
+	case "torrent-get":
+		args = map[string]interface{}{"torrents": []map[string]interface{}{{
+			"hashString":     "abc",
+			"name":           "Some.Torrent",
+			"downloadDir":    "/downloads",
+			"totalSize":      2048,
+			"downloadedEver": 1024,
+			"uploadedEver":   4096,
+			"status":         6,
+			"percentDone":    1.0,
+			"uploadRatio":    4.0,
+			"addedDate":      1,
+			"secondsSeeding": 86400,
+			"labels":         f.labels,
+			"files": []map[string]interface{}{
+				{"name": "Some.Torrent/file.mkv"},
+				{"name": "Some.Torrent/file.nfo"},
+			},
+			"trackerStats": []map[string]interface{}{
+				{"announce": "https://backup.example.org/announce", "tier": 1,
+					"lastAnnounceResult": "Backup", "seederCount": 30, "leecherCount": 3},
+				{"announce": "https://tracker.example.com:443/announce", "tier": 0,
+					"lastAnnounceResult": "Success", "seederCount": 10, "leecherCount": -1},
+			},
+		}}}
+	case "torrent-set":
+		f.labels = nil
+		for _, l := range req.Arguments["labels"].([]interface{}) {
+			f.labels = append(f.labels, l.(string))
+		}
+	case "torrent-remove":
+		f.removed = req.Arguments
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": "success", "arguments": args})
+}
+
+func newTestTransmission(t *testing.T, f *fakeTransmission) *Transmission {
+	t.Helper()
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return &Transmission{
+		User:       "user",
+		Password:   "pass",
+		log:        logger.GetLogger("transmission"),
+		clientType: "Transmission",
+		http:       srv.Client(),
+		rpcUrl:     srv.URL + "/transmission/rpc",
+	}
+}
+
+/* Tests */
+
+func TestTransmissionSessionId(t *testing.T) {
+	f := &fakeTransmission{sessionId: "first"}
+	c := newTestTransmission(t, f)
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	// a new session id is negotiated when the daemon hands out another one
+	f.sessionId = "second"
+	if _, err := c.GetTorrents(); err != nil {
+		t.Fatalf("get torrents: %v", err)
+	}
+
+	if f.conflicts != 2 {
+		t.Errorf("expected 2 session id conflicts, got %d", f.conflicts)
+	}
+	if c.sessionId != "second" {
+		t.Errorf("expected session id %q, got %q", "second", c.sessionId)
+	}
+	if want := []string{"session-get", "torrent-get"}; !slices.Equal(f.methods, want) {
+		t.Errorf("expected methods %v, got %v", want, f.methods)
+	}
+}
+
+func TestTransmissionUnsupportedRpcVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result":"success","arguments":{"version":"2.94","rpc-version":15}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &Transmission{log: logger.GetLogger("transmission"), http: srv.Client(), rpcUrl: srv.URL}
+	if err := c.Connect(); err == nil {
+		t.Fatal("expected an error for rpc version 15")
+	}
+}
+
+func TestTransmissionGetTorrents(t *testing.T) {
+	f := &fakeTransmission{labels: []string{"tv", "keep", "cross-seed"}}
+	c := newTestTransmission(t, f)
+
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		t.Fatalf("get torrents: %v", err)
+	}
+
+	tr, ok := torrents["abc"]
+	if !ok {
+		t.Fatalf("expected torrent abc, got %v", torrents)
+	}
+
+	if tr.Name != "Some.Torrent" || tr.Path != "/downloads" {
+		t.Errorf("unexpected name or path: %q, %q", tr.Name, tr.Path)
+	}
+	if tr.TotalBytes != 2048 || tr.DownloadedBytes != 1024 || tr.UploadedBytes != 4096 {
+		t.Errorf("unexpected sizes: %d, %d, %d", tr.TotalBytes, tr.DownloadedBytes, tr.UploadedBytes)
+	}
+	if tr.State != "seeding" || !tr.Seeding || !tr.Downloaded {
+		t.Errorf("unexpected state: %q (seeding: %v, downloaded: %v)", tr.State, tr.Seeding, tr.Downloaded)
+	}
+	if tr.Ratio != 4 || tr.SeedingDays != 1 {
+		t.Errorf("unexpected ratio or seeding days: %v, %v", tr.Ratio, tr.SeedingDays)
+	}
+	if want := []string{"/downloads/Some.Torrent/file.mkv", "/downloads/Some.Torrent/file.nfo"}; !slices.Equal(tr.Files, want) {
+		t.Errorf("expected files %v, got %v", want, tr.Files)
+	}
+
+	// the labels are tags, the first label is the label as well
+	if tr.Label != "tv" {
+		t.Errorf("expected label %q, got %q", "tv", tr.Label)
+	}
+	if want := []string{"tv", "keep", "cross-seed"}; !slices.Equal(tr.Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, tr.Tags)
+	}
+
+	// the tracker of the lowest tier is used, counts are the highest of all trackers
+	if tr.TrackerName != "example.com" || tr.TrackerStatus != "Success" {
+		t.Errorf("unexpected tracker: %q, %q", tr.TrackerName, tr.TrackerStatus)
+	}
+	if tr.Seeds != 30 || tr.Peers != 3 {
+		t.Errorf("unexpected seeds or peers: %d, %d", tr.Seeds, tr.Peers)
+	}
+}
+
+func TestTransmissionLabels(t *testing.T) {
+	f := &fakeTransmission{labels: []string{"tv", "keep"}}
+	c := newTestTransmission(t, f)
+
+	if err := c.SetTorrentLabel("abc", "movies", false); err != nil {
+		t.Fatalf("set torrent label: %v", err)
+	}
+	if want := []string{"movies", "keep"}; !slices.Equal(f.labels, want) {
+		t.Errorf("expected labels %v after relabel, got %v", want, f.labels)
+	}
+
+	if err := c.AddTags("abc", []string{"keep", "unregistered"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+	if want := []string{"movies", "keep", "unregistered"}; !slices.Equal(f.labels, want) {
+		t.Errorf("expected labels %v after adding tags, got %v", want, f.labels)
+	}
+
+	if err := c.RemoveTags("abc", []string{"keep", "missing"}); err != nil {
+		t.Fatalf("remove tags: %v", err)
+	}
+	if want := []string{"movies", "unregistered"}; !slices.Equal(f.labels, want) {
+		t.Errorf("expected labels %v after removing tags, got %v", want, f.labels)
+	}
+
+	// the first tag would become the label
+	if err := c.SetTorrentLabel("abc", "", false); err == nil {
+		t.Error("expected an error removing the label of a tagged torrent")
+	}
+}
+
+func TestTransmissionTagsWithoutLabel(t *testing.T) {
+	f := &fakeTransmission{}
+	c := newTestTransmission(t, f)
+
+	if err := c.AddTags("abc", []string{"low-seeds", "unregistered"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+	if want := []string{"low-seeds", "unregistered"}; !slices.Equal(f.labels, want) {
+		t.Errorf("expected labels %v after adding tags, got %v", want, f.labels)
+	}
+
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		t.Fatalf("get torrents: %v", err)
+	}
+	if tr := torrents["abc"]; !tr.HasAllTags("low-seeds", "unregistered") {
+		t.Errorf("expected added tags, got %v", tr.Tags)
+	}
+
+	if err := c.RemoveTags("abc", []string{"low-seeds", "unregistered"}); err != nil {
+		t.Fatalf("remove tags: %v", err)
+	}
+	if len(f.labels) != 0 {
+		t.Errorf("expected no labels after removing tags, got %v", f.labels)
+	}
+}
+
+func TestTransmissionRemoveTorrent(t *testing.T) {
+	f := &fakeTransmission{}
+	c := newTestTransmission(t, f)
+
+	removed, err := c.RemoveTorrent("abc", true)
+	if err != nil {
+		t.Fatalf("remove torrent: %v", err)
+	} else if !removed {
+		t.Fatal("expected torrent to be removed")
+	}
+
+	if want := []string{"torrent-stop", "torrent-start", "torrent-reannounce", "torrent-remove"}; !slices.Equal(f.methods, want) {
+		t.Errorf("expected methods %v, got %v", want, f.methods)
+	}
+	if f.removed["delete-local-data"] != true {
+		t.Errorf("expected data to be deleted, got %v", f.removed)
+	}
+	if ids, _ := f.removed["ids"].([]interface{}); len(ids) != 1 || ids[0] != "abc" {
+		t.Errorf("expected ids [abc], got %v", f.removed["ids"])
+	}
+}
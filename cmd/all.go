@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// clientArgs requires a single client argument, or none when running against all clients with --all.
+func clientArgs(cmd *cobra.Command, args []string) error {
+	if flagAll {
+		if flagPlanOut != "" {
+			return errors.New("--plan-out cannot be used with --all")
+		}
+
+		return cobra.NoArgs(cmd, args)
+	}
+
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
+// enabledClients returns the names of all enabled clients, sorted.
+func enabledClients() []string {
+	clientNames := make([]string, 0, len(config.Config.Clients))
+	for clientName, clientConfig := range config.Config.Clients {
+		if err := validateClientEnabled(clientConfig); err != nil {
+			continue
+		}
+
+		clientNames = append(clientNames, clientName)
+	}
+	sort.Strings(clientNames)
+
+	return clientNames
+}
+
+// runCommandAll runs command against every enabled client, continuing past failures of a client,
+// and logs a combined summary of the runs.
+func runCommandAll(log *logrus.Entry, command string) error {
+	clientNames := enabledClients()
+	if len(clientNames) == 0 {
+		return errors.New("no enabled clients found")
+	}
+
+	log.Infof("Running %s against %d clients: %v", command, len(clientNames), clientNames)
+
+	results := make([]*runResult, 0, len(clientNames))
+	failed := 0
+	skipped := 0
+
+	for _, clientName := range clientNames {
+		log.Info("==========")
+
+		// clients not supporting the command are skipped, they are not a failure
+		if supported, err := supportsCommand(command, clientName); err == nil && !supported {
+			log.Warnf("Skipping client %q, it does not support %s", clientName, command)
+			skipped++
+			continue
+		}
+
+		log.Infof("Running %s against client: %q", command, clientName)
+
+		result, err := runCommand(command, clientName, false)
+		writeMetricsTextfile(log, result)
+		if err != nil {
+			log.WithError(err).Errorf("Failed running %s against client: %q", command, clientName)
+			failed++
+		}
+
+		results = append(results, result)
+	}
+
+	// show combined summary
+	log.Info("==========")
+	log.Infof("Summary of %s against %d clients:", command, len(results))
+	if skipped > 0 {
+		log.Infof("Skipped %d clients not supporting %s", skipped, command)
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			log.Errorf("%s: failed after %s: %s", r.Client, r.Duration, r.Error)
+			continue
+		}
+
+		log.Infof("%s: %s in %s", r.Client, runSummary(r), r.Duration)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d clients failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// supportsCommand returns whether a client supports a command, retag requires a client supporting tags.
+func supportsCommand(command string, clientName string) (bool, error) {
+	if command != "retag" {
+		return true, nil
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", config.Config.Clients[clientName])
+	if err != nil {
+		return false, fmt.Errorf("determine client type: %w", err)
+	}
+
+	// load client object, without connecting to it
+	c, err := client.NewClient(*clientType, clientName, nil)
+	if err != nil {
+		return false, fmt.Errorf("initialize client: %w", err)
+	}
+
+	_, ok := c.(client.TagInterface)
+	return ok, nil
+}
+
+// runSummary describes the outcome of a run in a single line.
+func runSummary(r *runResult) string {
+	var summary string
+	switch r.Command {
+	case "clean":
+		summary = fmt.Sprintf("removed %d of %d torrents, reclaimed %s", r.Removed, r.Torrents,
+			humanize.IBytes(r.ReclaimedBytes))
+	case "relabel":
+		summary = fmt.Sprintf("relabeled %d of %d torrents", r.Relabeled, r.Torrents)
+	case "retag":
+		summary = fmt.Sprintf("retagged %d of %d torrents", r.Retagged, r.Torrents)
+	case "orphan":
+		summary = fmt.Sprintf("removed %d orphan files and %d orphan folders, reclaimed %s", r.OrphanFiles,
+			r.OrphanFolders, humanize.IBytes(r.ReclaimedBytes))
+		if r.OrphanFoldersNonEmpty > 0 {
+			summary += fmt.Sprintf(", kept %d non-empty folders", r.OrphanFoldersNonEmpty)
+		}
+		if len(r.OrphansSkipped) > 0 {
+			summary += fmt.Sprintf(", skipped %s", skippedString(r.OrphansSkipped))
+		}
+	default:
+		summary = fmt.Sprintf("%d torrents", r.Torrents)
+	}
+
+	summary += fmt.Sprintf(", %d failures", r.Failures)
+	if r.DryRun {
+		summary += " (dry-run)"
+	}
+
+	return summary
+}
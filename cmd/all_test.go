@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/autobrr/tqm/logger"
+)
+
+func TestRunCommandAllSkipsUnsupportedClients(t *testing.T) {
+	// deluge does not support tags, it must not be connected to
+	setupSnapshotConfig(t, `  deluge:
+    enabled: true
+    type: deluge
+    host: 127.0.0.1
+    port: 1
+    login: user
+    password: pass
+    filter: default
+`)
+
+	prevDryRun := flagDryRun
+	flagDryRun = true
+	t.Cleanup(func() {
+		flagDryRun = prevDryRun
+	})
+
+	if err := runCommandAll(logger.GetLogger("test"), "retag"); err != nil {
+		t.Errorf("expected clients not supporting retag to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/hardlinkfilemap"
+	"github.com/autobrr/tqm/journal"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/plan"
+	"github.com/autobrr/tqm/recycle"
+	"github.com/autobrr/tqm/sliceutils"
+	"github.com/autobrr/tqm/torrentfilemap"
+	"github.com/autobrr/tqm/tracker"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [PLAN]",
+	Short: "Apply a plan written by clean, relabel or retag",
+	Long:  `This command can be used to execute the actions of a plan written with --plan-out, skipping torrents that changed since the plan was written.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("apply")
+
+		// load plan
+		p, err := plan.Load(args[0])
+		if err != nil {
+			log.WithError(err).Fatalf("Failed loading plan: %q", args[0])
+		}
+
+		log.Infof("Loaded plan with %d %s actions for %q (created: %s)", len(p.Actions), p.Command, p.Client,
+			p.Created.Format(time.RFC3339))
+
+		// apply plan
+		result, err := runCommandFunc(p.Command, p.Client, false,
+			func(log *logrus.Entry, clientName string, result *runResult) error {
+				return runApply(log, p, result)
+			})
+		writeMetricsTextfile(log, result)
+		if err != nil {
+			log.WithError(err).Fatal("Failed applying plan")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().BoolVar(&flagForce, "force", false, "Remove torrents even when removal limits are exceeded")
+}
+
+func runApply(log *logrus.Entry, p *plan.Plan, result *runResult) error {
+	switch p.Command {
+	case "clean", "relabel", "retag":
+	default:
+		return fmt.Errorf("unsupported plan command: %q", p.Command)
+	}
+
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[p.Client]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", p.Client)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client recycle bin
+	var bin *recycle.Bin
+	if p.Command == "clean" {
+		bin, err = getClientRecycleBin(clientConfig)
+		if err != nil {
+			return fmt.Errorf("retrieve client recycle bin: %w", err)
+		} else if bin != nil {
+			log.Infof("Recycling data of removed torrents to: %q", bin.Path)
+		}
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, p.Client, nil)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", p.Client, err)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", p.Client, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	if p.Command == "relabel" {
+		// load client label path map
+		if err := c.LoadLabelPathMap(); err != nil {
+			return fmt.Errorf("load label path map: %w", err)
+		}
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	result.Torrents = len(torrents)
+
+	// check removal limits, plans must not get around them
+	if p.Command == "clean" {
+		limits, err := getClientRemovalLimits(p.Client, clientConfig)
+		if err != nil {
+			return fmt.Errorf("retrieve client removal limits: %w", err)
+		}
+
+		removals := make([]removalCandidate, 0, len(p.Actions))
+		for _, a := range p.Actions {
+			if t, ok := torrents[a.Hash]; ok && a.Action == plan.ActionRemove {
+				removals = append(removals, removalCandidate{hash: a.Hash, torrent: t})
+			}
+		}
+
+		if err := enforceRemovalLimits(log, result.DryRun, limits, removals, len(torrents),
+			countTorrentsByTracker(torrents)); err != nil {
+			return err
+		}
+	}
+
+	// data of planned removals is only deleted while its files remain unique to the removed torrents
+	var deleteData map[string]bool
+	if p.Command == "clean" {
+		deleteData, err = getPlannedDataRemovals(log, p, clientConfig, torrents)
+		if err != nil {
+			return fmt.Errorf("check planned removals are unique: %w", err)
+		}
+	}
+
+	// apply actions
+	applied := 0
+	changed := 0
+	failures := 0
+	var appliedBytes int64 = 0
+	failedFiles := make(map[string]bool)
+
+	for _, a := range p.Actions {
+		log.Info("-----")
+		log.Infof("Applying %s: %q - %s | Rule: %s", a.Action, a.Name, a.Reason, a.Rule)
+
+		// refuse to act on torrents that changed since planning
+		t, ok := torrents[a.Hash]
+		if !ok {
+			log.Warnf("Skipping torrent no longer in client: %q", a.Name)
+			result.addFailure(a.Name, errors.New("torrent no longer in client"))
+			changed++
+			continue
+		}
+
+		if changes := a.State.Changes(plan.NewState(&t)); len(changes) > 0 {
+			log.Warnf("Skipping torrent changed since planning: %q (%s)", a.Name, strings.Join(changes, ", "))
+			result.addFailure(a.Name, fmt.Errorf("changed since planning: %s", strings.Join(changes, ", ")))
+			changed++
+			continue
+		}
+
+		// files shared with a torrent that failed to be removed must be kept
+		removeData := deleteData[a.Hash] && !slices.ContainsFunc(t.Files, func(f string) bool { return failedFiles[f] })
+
+		if result.DryRun {
+			log.Warnf("Dry-run enabled, skipping %s...", a.Action)
+		} else if err := applyAction(log, c, &t, a, removeData, bin); err != nil {
+			log.WithError(err).Errorf("Failed applying %s: %+v", a.Action, t)
+			result.addFailure(a.Name, err)
+			for _, f := range t.Files {
+				failedFiles[f] = true
+			}
+			failures++
+			continue
+		} else {
+			journalAction(log, result, &t, a)
+			log.Info("Applied")
+		}
+
+		if a.Action == plan.ActionRemove {
+			removed := removedTorrent(&t, nil)
+			removed.Rule = a.Rule
+			result.RemovedTorrents = append(result.RemovedTorrents, removed)
+			if removeData {
+				appliedBytes += t.DownloadedBytes
+			}
+		}
+		applied++
+	}
+
+	// show result
+	log.Info("-----")
+	log.WithField("reclaimed_space", humanize.IBytes(uint64(appliedBytes))).
+		Infof("Applied %d of %d actions, %d torrents changed since planning and %d failures", applied,
+			len(p.Actions), changed, failures)
+
+	switch p.Command {
+	case "clean":
+		result.Removed = applied
+		result.ReclaimedBytes = uint64(appliedBytes)
+	case "relabel":
+		result.Relabeled = applied
+	case "retag":
+		result.Retagged = applied
+	}
+	result.Failures = changed + failures
+
+	if changed+failures > 0 {
+		return fmt.Errorf("%d of %d actions not applied", changed+failures, len(p.Actions))
+	}
+
+	return nil
+}
+
+// journalAction records an applied relabel or retag action in the journal.
+func journalAction(log *logrus.Entry, result *runResult, t *config.Torrent, a plan.Action) {
+	switch a.Action {
+	case plan.ActionRelabel:
+		result.addJournal(log, t, journal.Entry{
+			Action:   journal.ActionRelabel,
+			Rule:     a.Rule,
+			NewLabel: a.Label,
+			Hardlink: a.Hardlink,
+			NewTags:  t.Tags,
+		})
+	case plan.ActionRetag:
+		result.addJournal(log, t, journal.Entry{
+			Action:   journal.ActionRetag,
+			Rule:     a.Rule,
+			NewLabel: t.Label,
+			NewTags:  retaggedTags(t.Tags, a.AddTags, a.RemoveTags),
+		})
+	}
+}
+
+// getPlannedDataRemovals returns the hashes of planned removals whose data can be deleted. The maps of files are
+// rebuilt, as torrents may have been added since planning, and no data is deleted when the plan is stale.
+func getPlannedDataRemovals(log *logrus.Entry, p *plan.Plan, clientConfig map[string]interface{},
+	torrents map[string]config.Torrent) (map[string]bool, error) {
+	removals := make([]config.Torrent, 0, len(p.Actions))
+	for _, a := range p.Actions {
+		t, ok := torrents[a.Hash]
+		if !ok || len(a.State.Changes(plan.NewState(&t))) > 0 {
+			log.Warn("Torrents changed since planning, removing torrents without their data")
+			return map[string]bool{}, nil
+		}
+
+		if a.Action == plan.ActionRemove {
+			removals = append(removals, t)
+		}
+	}
+
+	// retrieve client filters
+	clientFilter, err := getClientFilter(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve client filter: %w", err)
+	}
+
+	// retrieve torrents of clients sharing data with the client
+	sharedTorrents, err := loadSharedTorrents(log, p.Client, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("load shared torrents: %w", err)
+	}
+
+	// create map of files associated to torrents (via hash)
+	tfm := torrentfilemap.New(torrents)
+	for _, t := range sharedTorrents {
+		tfm.Add(t)
+	}
+
+	var hfm hardlinkfilemap.HardlinkFileMapI = hardlinkfilemap.NewNoopHardlinkFileMap()
+	if sliceutils.StringSliceContains(clientFilter.MapHardlinksFor, "clean", true) {
+		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+		if err != nil {
+			return nil, fmt.Errorf("load client download path mappings: %w", err)
+		}
+
+		hfm = hardlinkfilemap.New(torrents, clientDownloadPathMapping)
+		for _, t := range sharedTorrents {
+			hfm.AddByTorrent(t)
+		}
+	}
+
+	// imagine all planned torrents removed, their files must have no other instances left
+	for _, t := range removals {
+		tfm.Remove(t)
+		hfm.RemoveByTorrent(t)
+	}
+
+	deleteData := make(map[string]bool, len(removals))
+	for _, t := range removals {
+		if !tfm.NoInstances(t) || !hfm.NoInstances(t) {
+			log.Warnf("Files of %q are no longer unique, removing torrent without its data", t.Name)
+			continue
+		}
+
+		deleteData[t.Hash] = true
+	}
+
+	return deleteData, nil
+}
+
+func applyAction(log *logrus.Entry, c client.Interface, t *config.Torrent, a plan.Action, deleteData bool,
+	bin *recycle.Bin) error {
+	switch a.Action {
+	case plan.ActionRemove:
+		// data is kept when recycling
+		removed, err := c.RemoveTorrent(t.Hash, deleteData && bin == nil)
+		if err != nil {
+			return fmt.Errorf("remove torrent: %w", err)
+		} else if !removed {
+			return errors.New("torrent was not removed")
+		}
+
+		// move data to the recycle bin
+		if deleteData && bin != nil {
+			moved, err := bin.MoveTorrentFiles(t.Files, t.Path)
+			if err != nil {
+				return fmt.Errorf("recycle, %d files moved: %w", moved, err)
+			}
+
+			log.Infof("Recycled %d files to: %q", moved, bin.Path)
+		}
+
+		time.Sleep(1 * time.Second)
+	case plan.ActionRelabel:
+		if err := c.SetTorrentLabel(t.Hash, a.Label, a.Hardlink); err != nil {
+			return fmt.Errorf("set torrent label: %w", err)
+		}
+
+		time.Sleep(5 * time.Second)
+	case plan.ActionRetag:
+		ct, ok := c.(client.TagInterface)
+		if !ok {
+			return errors.New("retagging is currently only supported for qbittorrent and transmission")
+		}
+
+		if err := ct.AddTags(t.Hash, a.AddTags); err != nil {
+			return fmt.Errorf("add tags: %w", err)
+		}
+
+		if err := ct.RemoveTags(t.Hash, a.RemoveTags); err != nil {
+			return fmt.Errorf("remove tags: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported action: %q", a.Action)
+	}
+
+	return nil
+}
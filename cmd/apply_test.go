@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/plan"
+)
+
+func TestPlannedDataRemovals(t *testing.T) {
+	setupSnapshotConfig(t)
+
+	torrents := map[string]config.Torrent{
+		"AAAA": {Hash: "AAAA", Name: "Unregistered.Show.S01", Files: []string{"/downloads/tv/Unregistered.Show.S01/e01.mkv"}},
+		"CCCC": {Hash: "CCCC", Name: "Seeded.Movie", Files: []string{"/downloads/movies/Seeded.Movie.mkv"}},
+		"GGGG": {Hash: "GGGG", Name: "Grouped.Movie", Files: []string{"/downloads/movies/Grouped.Movie.mkv"}},
+		"HHHH": {Hash: "HHHH", Name: "Grouped.Movie", Files: []string{"/downloads/movies/Grouped.Movie.mkv"}},
+	}
+
+	removal := func(h string) plan.Action {
+		t := torrents[h]
+		return plan.Action{Hash: h, Name: t.Name, Action: plan.ActionRemove, State: plan.NewState(&t)}
+	}
+
+	p := plan.New("snapshot", "clean", []plan.Action{removal("AAAA"), removal("CCCC"), removal("GGGG"), removal("HHHH")})
+
+	// a cross-seed of CCCC added since planning
+	torrents["FFFF"] = config.Torrent{Hash: "FFFF", Name: "Seeded.Movie", Files: []string{"/downloads/movies/Seeded.Movie.mkv"}}
+
+	deleteData, err := getPlannedDataRemovals(logger.GetLogger("test"), p, config.Config.Clients["snapshot"], torrents)
+	if err != nil {
+		t.Fatalf("planned data removals: %v", err)
+	}
+
+	// planned members of a group sharing files are removed together
+	for h, want := range map[string]bool{"AAAA": true, "CCCC": false, "GGGG": true, "HHHH": true} {
+		if deleteData[h] != want {
+			t.Errorf("expected data removal of %s to be %v", h, want)
+		}
+	}
+
+	// no data is deleted once a torrent of the plan changed
+	changed := torrents["HHHH"]
+	changed.Label = "movies"
+	torrents["HHHH"] = changed
+
+	deleteData, err = getPlannedDataRemovals(logger.GetLogger("test"), p, config.Config.Clients["snapshot"], torrents)
+	if err != nil {
+		t.Fatalf("planned data removals: %v", err)
+	}
+	if len(deleteData) != 0 {
+		t.Errorf("expected no data removals for a stale plan, got %v", deleteData)
+	}
+}
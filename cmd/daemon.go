@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/scheduler"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run commands on a schedule and serve the http api",
+	Long:  `This command can be used to run tqm in the foreground, running the commands configured in the schedule section of the config and serving the http api when configured.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("daemon")
+
+		// build schedule
+		s := scheduler.New()
+
+		clientNames := make([]string, 0, len(config.Config.Schedule))
+		for clientName := range config.Config.Schedule {
+			clientNames = append(clientNames, clientName)
+		}
+		sort.Strings(clientNames)
+
+		for _, clientName := range clientNames {
+			clientSchedule := config.Config.Schedule[clientName]
+
+			// validate client
+			clientConfig, ok := config.Config.Clients[clientName]
+			if !ok {
+				log.Fatalf("No client configuration found for scheduled client: %q", clientName)
+			}
+
+			if err := validateClientEnabled(clientConfig); err != nil {
+				log.WithError(err).Warnf("Skipping schedule of client: %q", clientName)
+				continue
+			}
+
+			// validate commands
+			for command := range clientSchedule {
+				if _, ok := commandFuncs[strings.ToLower(command)]; !ok {
+					log.Fatalf("Unsupported command scheduled for client %q: %q", clientName, command)
+				}
+			}
+
+			for _, command := range runnableCommands {
+				spec, ok := scheduleSpec(clientSchedule, command)
+				if !ok {
+					continue
+				}
+
+				schedule, err := scheduler.ParseSchedule(spec)
+				if err != nil {
+					log.WithError(err).Fatalf("Failed parsing %s schedule of client: %q", command, clientName)
+				}
+
+				s.Add(clientName, &scheduler.Job{
+					Name:     command,
+					Spec:     spec,
+					Schedule: schedule,
+					Run:      newScheduledJob(command, clientName),
+				})
+			}
+		}
+
+		if s.Jobs() == 0 && config.Config.Server.Listen == "" {
+			log.Fatal("No commands scheduled and server disabled, add a schedule or server section to your config")
+		}
+
+		// run until signalled
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// start server
+		var srv *server
+		if config.Config.Server.Listen != "" {
+			srv = newServer(config.Config.Server)
+			srv.Start()
+		}
+
+		go func() {
+			<-ctx.Done()
+			log.Info("Shutting down, waiting for running commands to finish...")
+		}()
+
+		log.Infof("Started with %d scheduled commands", s.Jobs())
+		s.Run(ctx)
+		<-ctx.Done()
+
+		if srv != nil {
+			srv.Shutdown()
+		}
+
+		// wait for commands triggered via the server
+		runLock.Lock()
+		log.Info("Stopped")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func scheduleSpec(clientSchedule config.ScheduleConfiguration, command string) (string, bool) {
+	for k, v := range clientSchedule {
+		if strings.EqualFold(k, command) && strings.TrimSpace(v) != "" {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+func newScheduledJob(command string, clientName string) func() error {
+	return func() error {
+		_, err := runCommand(command, clientName, false)
+		return err
+	}
+}
@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
+	"github.com/autobrr/tqm/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagFilterExpr string
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Work with filters",
+	Long:  `This command can be used to work with the configured filters.`,
+}
+
+var filterTestCmd = &cobra.Command{
+	Use:   "test [CLIENT]",
+	Short: "Evaluate a filter or expression against the torrents of a client",
+	Long:  `This command can be used to evaluate a filter, or an ad-hoc expression, against the torrents of a client without taking any actions.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(false)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("filter")
+
+		// test filter
+		if err := runFilterTest(log, args[0]); err != nil {
+			log.WithError(err).Fatal("Failed testing filter")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(filterCmd)
+	filterCmd.AddCommand(filterTestCmd)
+
+	filterTestCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	filterTestCmd.Flags().StringVar(&flagFilterExpr, "expr", "", "Expression to evaluate instead of a filter")
+}
+
+type filterTestRule struct {
+	match  *expression.Match
+	fields []string
+	check  func(t *config.Torrent) (bool, error)
+
+	// torrents matching the rule
+	matches int
+	// torrents whose outcome was decided by the rule
+	decided int
+}
+
+type filterTestRow struct {
+	torrent *config.Torrent
+	outcome string
+	rules   []*filterTestRule
+}
+
+func runFilterTest(log *logrus.Entry, clientName string) error {
+	if flagFilterExpr != "" && flagFilterName != "" {
+		return errors.New("only one of --filter and --expr can be set")
+	}
+
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// compile ad-hoc expression or filter
+	var (
+		adhoc *expression.Expression
+		exp   = new(expression.Expressions)
+	)
+
+	if flagFilterExpr != "" {
+		adhoc, err = expression.CompileExpression(flagFilterExpr)
+		if err != nil {
+			return fmt.Errorf("compile expression: %w", err)
+		}
+	} else {
+		var clientFilter *config.FilterConfiguration
+		if flagFilterName != "" {
+			clientFilter, err = getFilter(flagFilterName)
+		} else {
+			clientFilter, err = getClientFilter(clientConfig)
+		}
+		if err != nil {
+			return fmt.Errorf("retrieve filter: %w", err)
+		}
+
+		exp, err = expression.Compile(clientFilter)
+		if err != nil {
+			return fmt.Errorf("compile filters: %w", err)
+		}
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	// get free disk space (can/will be used by filters)
+	if clientFreeSpacePath != nil {
+		if _, err := c.GetCurrentFreeSpace(*clientFreeSpacePath); err != nil {
+			log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
+		}
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	}
+
+	if err := setTorrentState(log, clientName, torrents, false, false); err != nil {
+		return fmt.Errorf("set torrent state: %w", err)
+	}
+
+	// sort torrents by name for stable output
+	sorted := make([]*config.Torrent, 0, len(torrents))
+	for h := range torrents {
+		t := torrents[h]
+		sorted = append(sorted, &t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name == sorted[j].Name {
+			return sorted[i].Hash < sorted[j].Hash
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if adhoc != nil {
+		return printExpressionTest(w, adhoc, sorted)
+	}
+
+	return printFilterTest(w, c, exp, sorted)
+}
+
+func printExpressionTest(w *tabwriter.Writer, e *expression.Expression, torrents []*config.Torrent) error {
+	fields := e.Fields()
+
+	fmt.Fprintln(w, strings.Join(append([]string{"Name"}, fields...), "\t"))
+
+	matched := 0
+	for _, t := range torrents {
+		match, err := expression.CheckTorrentSingleMatch(t, []*expression.Expression{e})
+		if err != nil {
+			return fmt.Errorf("check %q: %w", t.Name, err)
+		} else if match == nil {
+			continue
+		}
+
+		values := []string{t.Name}
+		for _, f := range fields {
+			values = append(values, formatFieldValue(expression.FieldValue(t, f)))
+		}
+
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+		matched++
+	}
+
+	fmt.Fprintf(w, "\n%d of %d torrents matched: %s\n", matched, len(torrents), e.Text)
+	return nil
+}
+
+func printFilterTest(w *tabwriter.Writer, c client.Interface, exp *expression.Expressions,
+	torrents []*config.Torrent) error {
+	// rules of the filter, keyed by their kind and index
+	rules := make([]*filterTestRule, 0)
+	rulesByKey := make(map[string]*filterTestRule)
+
+	addRule := func(m *expression.Match, fields []string, check func(t *config.Torrent) (bool, error)) {
+		r := &filterTestRule{match: m, fields: fields, check: check}
+		rules = append(rules, r)
+		rulesByKey[ruleKey(m)] = r
+	}
+
+	for _, e := range exp.Ignores {
+		e := e
+		addRule(e.Match("ignore"), e.Fields(), func(t *config.Torrent) (bool, error) {
+			m, err := expression.CheckTorrentSingleMatch(t, []*expression.Expression{e})
+			return m != nil, err
+		})
+	}
+	for _, e := range exp.Removes {
+		e := e
+		addRule(e.Match("remove"), e.Fields(), func(t *config.Torrent) (bool, error) {
+			m, err := expression.CheckTorrentSingleMatch(t, []*expression.Expression{e})
+			return m != nil, err
+		})
+	}
+	for _, le := range exp.Labels {
+		le := le
+		addRule(le.Match(), le.Fields(), func(t *config.Torrent) (bool, error) {
+			return expression.CheckTorrentAllMatch(t, le.Updates)
+		})
+	}
+	for _, te := range exp.Tags {
+		te := te
+		addRule(te.Match(), te.Fields(), func(t *config.Torrent) (bool, error) {
+			return expression.CheckTorrentAllMatch(t, te.Updates)
+		})
+	}
+
+	// evaluate torrents
+	rows := make([]filterTestRow, 0)
+	addRow := func(t *config.Torrent, outcome string, matches ...*expression.Match) {
+		row := filterTestRow{torrent: t, outcome: outcome}
+		for _, m := range matches {
+			if r, ok := rulesByKey[ruleKey(m)]; ok {
+				r.decided++
+				row.rules = append(row.rules, r)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	for _, t := range torrents {
+		// count matches of every rule
+		for _, r := range rules {
+			match, err := r.check(t)
+			if err != nil {
+				return fmt.Errorf("check %q: %s: %w", t.Name, r.match, err)
+			} else if match {
+				r.matches++
+			}
+		}
+
+		// determine outcome
+		ignore, ignoreMatch, err := c.ShouldIgnore(t)
+		if err != nil {
+			return fmt.Errorf("check ignore: %q: %w", t.Name, err)
+		}
+
+		if ignore && !(config.Config.BypassIgnoreIfUnregistered && t.IsUnregistered()) {
+			addRow(t, "ignore", ignoreMatch)
+		} else if remove, removeMatch, err := c.ShouldRemove(t); err != nil {
+			return fmt.Errorf("check remove: %q: %w", t.Name, err)
+		} else if remove {
+			addRow(t, "remove", removeMatch)
+		}
+
+		if label, relabel, labelMatch, err := c.ShouldRelabel(t); err != nil {
+			return fmt.Errorf("check relabel: %q: %w", t.Name, err)
+		} else if relabel && label != t.Label {
+			addRow(t, "relabel: "+label, labelMatch)
+		}
+
+		if ct, ok := c.(client.TagInterface); ok {
+			if retagInfo, retag, err := ct.ShouldRetag(t); err != nil {
+				return fmt.Errorf("check retag: %q: %w", t.Name, err)
+			} else if retag {
+				addRow(t, "retag: "+retagReason(retagInfo), retagInfo.Matches...)
+			}
+		}
+	}
+
+	// show outcomes
+	fmt.Fprintln(w, "Name\tOutcome\tRule\tFields")
+	for _, row := range rows {
+		refs := make([]string, 0, len(row.rules))
+		values := make([]string, 0)
+		seen := make(map[string]bool)
+
+		for _, r := range row.rules {
+			refs = append(refs, ruleRef(r.match))
+			for _, f := range r.fields {
+				if seen[f] {
+					continue
+				}
+				seen[f] = true
+				values = append(values, f+"="+formatFieldValue(expression.FieldValue(row.torrent, f)))
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.torrent.Name, row.outcome, strings.Join(refs, ", "),
+			strings.Join(values, " "))
+	}
+
+	// show rule counts
+	fmt.Fprintf(w, "\nRule\tMatches\tDecided\tExpression\n")
+	for _, r := range rules {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", ruleRef(r.match), r.matches, r.decided, r.match.Expression)
+	}
+
+	fmt.Fprintf(w, "\n%d torrents evaluated, %d outcomes\n", len(torrents), len(rows))
+	return nil
+}
+
+func ruleKey(m *expression.Match) string {
+	return fmt.Sprintf("%s/%d", m.Kind, m.Index)
+}
+
+func ruleRef(m *expression.Match) string {
+	if m.Name != "" {
+		return fmt.Sprintf("%s[%d] %s", m.Kind, m.Index, m.Name)
+	}
+
+	return fmt.Sprintf("%s[%d]", m.Kind, m.Index)
+}
+
+func formatFieldValue(v interface{}) string {
+	switch vv := v.(type) {
+	case []string:
+		return "[" + strings.Join(vv, ", ") + "]"
+	case float32:
+		return fmt.Sprintf("%.3f", vv)
+	case float64:
+		return fmt.Sprintf("%.3f", vv)
+	case string:
+		return fmt.Sprintf("%q", vv)
+	default:
+		return fmt.Sprintf("%v", vv)
+	}
+}
@@ -1,38 +1,238 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/autobrr/tqm/client"
 	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
 	"github.com/autobrr/tqm/hardlinkfilemap"
+	"github.com/autobrr/tqm/journal"
+	"github.com/autobrr/tqm/notification"
+	"github.com/autobrr/tqm/plan"
+	"github.com/autobrr/tqm/recycle"
+	"github.com/autobrr/tqm/state"
 	"github.com/autobrr/tqm/torrentfilemap"
 
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 )
 
-func removeSlice(slice []string, remove []string) []string {
-	for _, item := range remove {
-		for i, v := range slice {
-			if v == item {
-				slice = append(slice[:i], slice[i+1:]...)
-			}
+func removedTorrent(t *config.Torrent, match *expression.Match) notification.Torrent {
+	return notification.Torrent{
+		Hash:        t.Hash,
+		Name:        t.Name,
+		Tracker:     t.TrackerName,
+		Ratio:       t.Ratio,
+		SeedingDays: t.SeedingDays,
+		Bytes:       t.DownloadedBytes,
+		Rule:        match.String(),
+	}
+}
+
+func matchesString(matches []*expression.Match) string {
+	rules := make([]string, 0, len(matches))
+	for _, m := range matches {
+		rules = append(rules, m.String())
+	}
+
+	return strings.Join(rules, "; ")
+}
+
+func removeReason(t *config.Torrent, unregistered bool, crossSeed bool) string {
+	reason := "matched remove filter"
+	if unregistered {
+		reason += fmt.Sprintf(" (unregistered: %q)", t.TrackerStatus)
+	}
+
+	if crossSeed {
+		reason += ", no other instances remain after removing cross-seeds"
+	}
+
+	return reason
+}
+
+func retagReason(retagInfo client.RetagInfo) string {
+	parts := make([]string, 0, 2)
+	if len(retagInfo.Add) > 0 {
+		parts = append(parts, "add tags: "+strings.Join(retagInfo.Add, ", "))
+	}
+	if len(retagInfo.Remove) > 0 {
+		parts = append(parts, "remove tags: "+strings.Join(retagInfo.Remove, ", "))
+	}
+
+	return strings.Join(parts, " / ")
+}
+
+// retaggedTags returns the tags of a torrent after adding and removing tags, leaving tags untouched.
+func retaggedTags(tags []string, add []string, remove []string) []string {
+	retagged := make([]string, 0, len(tags)+len(add))
+	for _, tag := range tags {
+		if !slices.Contains(remove, tag) {
+			retagged = append(retagged, tag)
+		}
+	}
+
+	for _, tag := range add {
+		if !slices.Contains(retagged, tag) {
+			retagged = append(retagged, tag)
+		}
+	}
+
+	return retagged
+}
+
+// state files are stored within the config folder
+const (
+	unregisteredStateFile = "unregistered.json"
+	statsStateFile        = "stats.json"
+	journalFile           = "journal.jsonl"
+)
+
+// setTorrentState sets the fields of torrents tracked across runs, the state is only updated when persist is set,
+// which commands never do during a dry-run or when writing a plan. It is updated once per client per run of commands.
+// The unregistered state is only updated when trackUnregistered is set, as checking torrents may query tracker apis.
+func setTorrentState(log *logrus.Entry, clientName string, torrents map[string]config.Torrent, persist bool,
+	trackUnregistered bool) error {
+	// torrents are checked at most once whether they are unregistered
+	config.CacheUnregistered(torrents)
+
+	stats, err := state.LoadStats(filepath.Join(flagConfigFolder, statsStateFile))
+	if err != nil {
+		return fmt.Errorf("load stats: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case !persist:
+	case !stats.Due(clientName, now):
+		log.Debugf("Torrent state updated within the last %s, skipping update", state.RecordInterval)
+		persist = false
+	}
+
+	if err := setUnregisteredSince(log, clientName, torrents, persist && trackUnregistered, now); err != nil {
+		return err
+	}
+
+	return setTorrentStats(log, clientName, stats, torrents, persist, now)
+}
+
+// setTorrentStats sets the fields of torrents derived from the stats history of the client,
+// a sample of every torrent is only recorded and saved when persist is set.
+func setTorrentStats(log *logrus.Entry, clientName string, stats *state.Stats, torrents map[string]config.Torrent,
+	persist bool, now time.Time) error {
+	if persist {
+		stats.Record(clientName, torrents, now)
+		if err := stats.Save(); err != nil {
+			return fmt.Errorf("save stats: %w", err)
 		}
 	}
-	return slice
+
+	for h, t := range torrents {
+		stats.Apply(clientName, &t, now)
+		torrents[h] = t
+	}
+
+	log.Debugf("Loaded stats history of %d torrents", len(stats.Clients[clientName]))
+	return nil
+}
+
+// tracksUnregistered returns whether the filters or the free space target reference UnregisteredSinceHours,
+// requiring torrents to be checked for the unregistered state.
+func tracksUnregistered(exp *expression.Expressions, target *freeSpaceTarget) bool {
+	if exp.References("UnregisteredSinceHours") {
+		return true
+	}
+
+	return target != nil && target.Sort.References("UnregisteredSinceHours")
+}
+
+// setUnregisteredSince sets UnregisteredSinceHours of torrents from the unregistered state of the client,
+// the state is only updated and saved when persist is set.
+func setUnregisteredSince(log *logrus.Entry, clientName string, torrents map[string]config.Torrent, persist bool,
+	now time.Time) error {
+	u, err := state.LoadUnregistered(filepath.Join(flagConfigFolder, unregisteredStateFile))
+	if err != nil {
+		return fmt.Errorf("load unregistered state: %w", err)
+	}
+
+	if persist {
+		if failed := u.Update(clientName, torrents, now); failed > 0 {
+			log.Warnf("Failed checking whether %d torrents are unregistered, keeping their unregistered state", failed)
+		}
+		if err := u.Save(); err != nil {
+			return fmt.Errorf("save unregistered state: %w", err)
+		}
+	}
+
+	unregistered := 0
+	for h, t := range torrents {
+		since, ok := u.Since(clientName, h)
+		if !ok {
+			continue
+		}
+
+		t.UnregisteredSinceHours = float32(now.Sub(since).Hours())
+		torrents[h] = t
+		unregistered++
+	}
+
+	log.Debugf("Loaded unregistered state of %d torrents", unregistered)
+	return nil
+}
+
+// orderTorrents returns the hashes of torrents sorted by name, then by the order of the filter when configured.
+func orderTorrents(order *expression.Sort, torrents map[string]config.Torrent) ([]string, error) {
+	hashes := make([]string, 0, len(torrents))
+	for h := range torrents {
+		hashes = append(hashes, h)
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := torrents[hashes[i]], torrents[hashes[j]]
+		if a.Name == b.Name {
+			return a.Hash < b.Hash
+		}
+		return a.Name < b.Name
+	})
+
+	if order == nil {
+		return hashes, nil
+	}
+
+	if err := expression.SortTorrents(order, hashes, func(h string) *config.Torrent {
+		t := torrents[h]
+		return &t
+	}); err != nil {
+		return nil, fmt.Errorf("order torrents: %w", err)
+	}
+
+	return hashes, nil
 }
 
 // retag torrent that meet required filters
-func retagEligibleTorrents(log *logrus.Entry, c client.TagInterface, torrents map[string]config.Torrent) error {
+func retagEligibleTorrents(log *logrus.Entry, c client.TagInterface, torrents map[string]config.Torrent,
+	result *runResult, order *expression.Sort) error {
 	// vars
 	ignoredTorrents := 0
 	retaggedTorrents := 0
 	errorRetaggedTorrents := 0
 
+	hashes, err := orderTorrents(order, torrents)
+	if err != nil {
+		return err
+	}
+
 	// iterate torrents
-	for h, t := range torrents {
+	for _, h := range hashes {
+		t := torrents[h]
 		// should we retag torrent?
 		retagInfo, retag, err := c.ShouldRetag(&t)
 		if err != nil {
@@ -47,31 +247,63 @@ func retagEligibleTorrents(log *logrus.Entry, c client.TagInterface, torrents ma
 		}
 
 		// retag
+		rule := matchesString(retagInfo.Matches)
+		result.addPlanned(&t, plan.Action{
+			Action:     plan.ActionRetag,
+			Rule:       rule,
+			Reason:     retagReason(retagInfo),
+			AddTags:    retagInfo.Add,
+			RemoveTags: retagInfo.Remove,
+		})
+
+		newTags := retaggedTags(t.Tags, retagInfo.Add, retagInfo.Remove)
+
 		log.Info("-----")
-		log.Infof("Retagging: %q - New Tags: %s", t.Name, strings.Join(append(removeSlice(t.Tags, retagInfo.Remove), retagInfo.Add...), ", "))
+		log.Infof("Retagging: %q - New Tags: %s | Rule: %s", t.Name, strings.Join(newTags, ", "), rule)
 		log.Infof("Ratio: %.3f / Seed days: %.3f / Seeds: %d / Label: %s / Tags: %s / Tracker: %s / "+
 			"Tracker Status: %q", t.Ratio, t.SeedingDays, t.Seeds, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, t.TrackerStatus)
 
-		if !flagDryRun {
-			error := 0
+		if !result.DryRun {
 			if err := c.AddTags(t.Hash, retagInfo.Add); err != nil {
-				log.WithError(err).Fatalf("Failed adding tags to torrent: %+v", t)
-				error = 1
+				log.WithError(err).Errorf("Failed adding tags to torrent: %+v", t)
+				result.addFailure(t.Name, err)
+				errorRetaggedTorrents++
 				continue
 			}
 
 			if err := c.RemoveTags(t.Hash, retagInfo.Remove); err != nil {
-				log.WithError(err).Fatalf("Failed remove tags from torrent: %+v", t)
-				error = 1
+				log.WithError(err).Errorf("Failed remove tags from torrent: %+v", t)
+				result.addFailure(t.Name, err)
+				// the added tags were applied
+				result.addJournal(log, &t, journal.Entry{
+					Action:   journal.ActionRetag,
+					Rule:     rule,
+					NewLabel: t.Label,
+					NewTags:  retaggedTags(t.Tags, retagInfo.Add, nil),
+				})
+				errorRetaggedTorrents++
 				continue
 			}
 
-			errorRetaggedTorrents += error
+			result.addJournal(log, &t, journal.Entry{
+				Action:   journal.ActionRetag,
+				Rule:     rule,
+				NewLabel: t.Label,
+				NewTags:  newTags,
+			})
+
 			log.Info("Retagged")
 		} else {
 			log.Warn("Dry-run enabled, skipping retag...")
 		}
 
+		notifyEvent(log, result, notification.Event{
+			Name:        t.Name,
+			Hash:        t.Hash,
+			AddedTags:   retagInfo.Add,
+			RemovedTags: retagInfo.Remove,
+		})
+
 		retaggedTorrents++
 	}
 
@@ -79,22 +311,32 @@ func retagEligibleTorrents(log *logrus.Entry, c client.TagInterface, torrents ma
 	log.Info("-----")
 	log.Infof("Ignored torrents: %d", ignoredTorrents)
 	log.Infof("Retagged torrents: %d, %d failures", retaggedTorrents, errorRetaggedTorrents)
+
+	result.Ignored = ignoredTorrents
+	result.Retagged = retaggedTorrents
+	result.Failures = errorRetaggedTorrents
 	return nil
 }
 
 // relabel torrent that meet required filters
 func relabelEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent,
-	tfm *torrentfilemap.TorrentFileMap) error {
+	tfm *torrentfilemap.TorrentFileMap, result *runResult, order *expression.Sort) error {
 	// vars
 	ignoredTorrents := 0
 	nonUniqueTorrents := 0
 	relabeledTorrents := 0
 	errorRelabelTorrents := 0
 
+	hashes, err := orderTorrents(order, torrents)
+	if err != nil {
+		return err
+	}
+
 	// iterate torrents
-	for h, t := range torrents {
+	for _, h := range hashes {
+		t := torrents[h]
 		// should we relabel torrent?
-		label, relabel, err := c.ShouldRelabel(&t)
+		label, relabel, match, err := c.ShouldRelabel(&t)
 		if err != nil {
 			// error while determining whether to relabel torrent
 			log.WithError(err).Errorf("Failed determining whether to relabel: %+v", t)
@@ -125,28 +367,53 @@ func relabelEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map
 		}
 
 		// relabel
+		result.addPlanned(&t, plan.Action{
+			Action:   plan.ActionRelabel,
+			Rule:     match.String(),
+			Reason:   fmt.Sprintf("label %q -> %q", t.Label, label),
+			Label:    label,
+			Hardlink: hardlink,
+		})
+
 		log.Info("-----")
 		if hardlink {
-			log.Infof("Relabeling: %q - %s | with hardlinks to: %q", t.Name, label, c.LabelPathMap()[label])
+			log.Infof("Relabeling: %q - %s | with hardlinks to: %q | Rule: %s", t.Name, label,
+				c.LabelPathMap()[label], match)
 		} else {
-			log.Infof("Relabeling: %q - %s", t.Name, label)
+			log.Infof("Relabeling: %q - %s | Rule: %s", t.Name, label, match)
 		}
 		log.Infof("Ratio: %.3f / Seed days: %.3f / Seeds: %d / Label: %s / Tags: %s / Tracker: %s / "+
 			"Tracker Status: %q", t.Ratio, t.SeedingDays, t.Seeds, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, t.TrackerStatus)
 
-		if !flagDryRun {
+		if !result.DryRun {
 			if err := c.SetTorrentLabel(t.Hash, label, hardlink); err != nil {
-				log.WithError(err).Fatalf("Failed relabeling torrent: %+v", t)
+				log.WithError(err).Errorf("Failed relabeling torrent: %+v", t)
+				result.addFailure(t.Name, err)
 				errorRelabelTorrents++
 				continue
 			}
 
+			result.addJournal(log, &t, journal.Entry{
+				Action:   journal.ActionRelabel,
+				Rule:     match.String(),
+				NewLabel: label,
+				Hardlink: hardlink,
+				NewTags:  t.Tags,
+			})
+
 			log.Info("Relabeled")
 			time.Sleep(5 * time.Second)
 		} else {
 			log.Warn("Dry-run enabled, skipping relabel...")
 		}
 
+		notifyEvent(log, result, notification.Event{
+			Name:     t.Name,
+			Hash:     t.Hash,
+			OldLabel: t.Label,
+			NewLabel: label,
+		})
+
 		relabeledTorrents++
 	}
 
@@ -157,67 +424,63 @@ func relabelEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map
 		log.Infof("Non-unique torrents: %d", nonUniqueTorrents)
 	}
 	log.Infof("Relabeled torrents: %d, %d failures", relabeledTorrents, errorRelabelTorrents)
+
+	result.Ignored = ignoredTorrents
+	result.NonUnique = nonUniqueTorrents
+	result.Relabeled = relabeledTorrents
+	result.Failures = errorRelabelTorrents
 	return nil
 }
 
 // remove torrents that meet remove filters
 func removeEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent,
-	tfm *torrentfilemap.TorrentFileMap, hfm hardlinkfilemap.HardlinkFileMapI) error {
+	tfm *torrentfilemap.TorrentFileMap, hfm hardlinkfilemap.HardlinkFileMapI, result *runResult,
+	order *expression.Sort, target *freeSpaceTarget, limits *config.RemovalLimitsConfiguration, bin *recycle.Bin) error {
 	// vars
 	ignoredTorrents := 0
 	hardRemoveTorrents := 0
 	errorRemoveTorrents := 0
 	var removedTorrentBytes int64 = 0
 
-	// helper function to remove torrent
-	removeTorrent := func(h string, t *config.Torrent) {
-		// remove the torrent
+	// torrents are queued for removal first, so removal limits can be checked before removing anything
+	removals := make([]removalCandidate, 0)
+	trackerTorrents := countTorrentsByTracker(torrents)
+	clientTorrents := len(torrents)
+
+	// whether torrents meeting the remove filters are unregistered, worked out once per torrent
+	unregistered := make(map[string]bool)
+
+	// helper function to queue a torrent for removal, freeing the given bytes
+	queueRemoval := func(h string, t *config.Torrent, match *expression.Match, crossSeed bool, freedBytes int64) {
+		result.addPlanned(t, plan.Action{
+			Action: plan.ActionRemove,
+			Rule:   match.String(),
+			Reason: removeReason(t, unregistered[h], crossSeed),
+		})
+
 		log.Info("-----")
 		if !t.FreeSpaceSet {
-			log.Infof("removing: %q - %s", t.Name, humanize.IBytes(uint64(t.DownloadedBytes)))
+			log.Infof("removing: %q - %s | Rule: %s", t.Name, humanize.IBytes(uint64(t.DownloadedBytes)), match)
 		} else {
 			// show current free-space as well
-			log.Infof("removing: %q - %s - %.2f GB", t.Name,
-				humanize.IBytes(uint64(t.DownloadedBytes)), t.FreeSpaceGB())
+			log.Infof("removing: %q - %s - %.2f GB | Rule: %s", t.Name,
+				humanize.IBytes(uint64(t.DownloadedBytes)), t.FreeSpaceGB(), match)
 		}
 
 		log.Infof("Ratio: %.3f / Seed days: %.3f / Seeds: %d / Label: %s / Tags: %s / Tracker: %s / "+
 			"Tracker Status: %q", t.Ratio, t.SeedingDays, t.Seeds, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, t.TrackerStatus)
 
-		if !flagDryRun {
-			// do remove
-			removed, err := c.RemoveTorrent(t.Hash, true)
-			if err != nil {
-				log.WithError(err).Fatalf("Failed removing torrent: %+v", t)
-				// dont remove from torrents file map, but prevent further operations on this torrent
-				delete(torrents, h)
-				errorRemoveTorrents++
-				return
-			} else if !removed {
-				log.Error("Failed removing torrent...")
-				// dont remove from torrents file map, but prevent further operations on this torrent
-				delete(torrents, h)
-				errorRemoveTorrents++
-				return
-			} else {
-				log.Info("Removed")
-
-				// increase free space
-				if t.FreeSpaceSet {
-					log.Tracef("Increasing free space by: %s", humanize.IBytes(uint64(t.DownloadedBytes)))
-					c.AddFreeSpace(t.DownloadedBytes)
-					log.Tracef("New free space: %.2f GB", c.GetFreeSpace())
-				}
-
-				time.Sleep(1 * time.Second)
-			}
-		} else {
-			log.Warn("Dry-run enabled, skipping remove...")
+		// increase free space, rolled back when the removal fails
+		if !t.FreeSpaceSet {
+			freedBytes = 0
+		} else if freedBytes > 0 {
+			log.Tracef("Increasing free space by: %s", humanize.IBytes(uint64(freedBytes)))
+			c.AddFreeSpace(freedBytes)
+			log.Tracef("New free space: %.2f GB", c.GetFreeSpace())
 		}
 
-		// increased hard removed counters
-		removedTorrentBytes += t.DownloadedBytes
-		hardRemoveTorrents++
+		removals = append(removals, removalCandidate{hash: h, torrent: *t, match: match, crossSeed: crossSeed,
+			freedBytes: freedBytes})
 
 		// remove the torrent from the torrent maps
 		tfm.Remove(*t)
@@ -225,26 +488,43 @@ func removeEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[
 		delete(torrents, h)
 	}
 
+	// in target mode removals are queued, ranked and removed until the free space target is met
+	targeted := make([][]removalCandidate, 0)
+	if target != nil {
+		log.Infof("Free space target: %.2f GB, current: %.2f GB, removing candidates ordered by: %s",
+			target.GB, c.GetFreeSpace(), target.Sort)
+	}
+
+	hashes, err := orderTorrents(order, torrents)
+	if err != nil {
+		return err
+	}
+
 	// iterate torrents
 	canidates := make(map[string]config.Torrent)
-	for h, t := range torrents {
+	canidateHashes := make([]string, 0)
+	canidateMatches := make(map[string]*expression.Match)
+	for _, h := range hashes {
+		t := torrents[h]
+		isUnregistered := sync.OnceValue(t.IsUnregistered)
+
 		// should we ignore this torrent?
-		ignore, err := c.ShouldIgnore(&t)
+		ignore, ignoreMatch, err := c.ShouldIgnore(&t)
 		if err != nil {
 			// error while determining whether to ignore torrent
 			log.WithError(err).Errorf("Failed determining whether to ignore: %+v", t)
 			delete(torrents, h)
 			continue
-		} else if ignore && !(config.Config.BypassIgnoreIfUnregistered && t.IsUnregistered()) {
+		} else if ignore && !(config.Config.BypassIgnoreIfUnregistered && isUnregistered()) {
 			// torrent met ignore filter
-			log.Tracef("Ignoring torrent %s: %s", h, t.Name)
+			log.Tracef("Ignoring torrent %s: %s | Rule: %s", h, t.Name, ignoreMatch)
 			delete(torrents, h)
 			ignoredTorrents++
 			continue
 		}
 
 		// should we remove this torrent?
-		remove, err := c.ShouldRemove(&t)
+		remove, match, err := c.ShouldRemove(&t)
 		if err != nil {
 			log.WithError(err).Errorf("Failed determining whether to remove: %+v", t)
 			// dont do any further operations on this torrent, but keep in the torrent file map
@@ -257,22 +537,32 @@ func removeEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[
 		}
 
 		// torrent meets the remove filters
+		unregistered[h] = isUnregistered()
 
 		// are the files unique and eligible for a hard deletion (remove data)
 		if !tfm.IsUnique(t) {
-			log.Warnf("Skipping non unique torrent | Name: %s / Label: %s / Tags: %s / Tracker: %s", t.Name, t.Label, strings.Join(t.Tags, ", "), t.TrackerName)
+			log.Warnf("Skipping non unique torrent | Name: %s / Label: %s / Tags: %s / Tracker: %s | Rule: %s", t.Name, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, match)
 			canidates[h] = t
+			canidateHashes = append(canidateHashes, h)
+			canidateMatches[h] = match
 			continue
 		}
 
 		// are the files not hardlinked to other torrents
 		if !hfm.IsTorrentUnique(t) {
-			log.Warnf("Skipping non unique torrent (hardlinked) | Name: %s / Label: %s / Tags: %s / Tracker: %s", t.Name, t.Label, strings.Join(t.Tags, ", "), t.TrackerName)
+			log.Warnf("Skipping non unique torrent (hardlinked) | Name: %s / Label: %s / Tags: %s / Tracker: %s | Rule: %s", t.Name, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, match)
 			canidates[h] = t
+			canidateHashes = append(canidateHashes, h)
+			canidateMatches[h] = match
+			continue
+		}
+
+		if target != nil {
+			targeted = append(targeted, []removalCandidate{{hash: h, torrent: t, match: match}})
 			continue
 		}
 
-		removeTorrent(h, &t)
+		queueRemoval(h, &t, match, false, t.DownloadedBytes)
 	}
 
 	log.Info("========================================")
@@ -288,17 +578,155 @@ func removeEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[
 	}
 
 	// check again for unique torrents
-	removedCanidates := 0
-	for h, t := range canidates {
-		noInstances := tfm.NoInstances(t) && hfm.NoInstances(t)
+	if target != nil {
+		// canidates sharing files can only be removed together
+		for _, group := range groupTorrentsByFiles(canidates) {
+			noInstances := true
+			members := make([]removalCandidate, 0, len(group))
+			for _, h := range group {
+				t := canidates[h]
+				if !tfm.NoInstances(t) || !hfm.NoInstances(t) {
+					log.Tracef("%s still not unique unique", t.Name)
+					noInstances = false
+					break
+				}
+
+				members = append(members, removalCandidate{hash: h, torrent: t, match: canidateMatches[h], crossSeed: true})
+			}
+
+			if noInstances {
+				targeted = append(targeted, members)
+			}
+		}
+	} else {
+		for _, h := range canidateHashes {
+			t := canidates[h]
+			noInstances := tfm.NoInstances(t) && hfm.NoInstances(t)
+
+			if !noInstances {
+				log.Tracef("%s still not unique unique", t.Name)
+				continue
+			}
+
+			queueRemoval(h, &t, canidateMatches[h], true, t.DownloadedBytes)
+		}
+	}
+
+	// remove ranked candidates until the free space target is met
+	keptCanidates := 0
+	keptCrossSeeds := 0
+	if target != nil {
+		for _, group := range targeted {
+			if err := expression.SortTorrents(target.Sort, group, removalCandidate.Torrent); err != nil {
+				return fmt.Errorf("sort candidates: %w", err)
+			}
+		}
+		if err := expression.SortTorrents(target.Sort, targeted, func(group []removalCandidate) *config.Torrent {
+			// groups are ranked by their first member
+			return group[0].Torrent()
+		}); err != nil {
+			return fmt.Errorf("sort candidates: %w", err)
+		}
+
+		for i, group := range targeted {
+			if c.GetFreeSpace() >= target.GB {
+				for _, rest := range targeted[i:] {
+					keptCanidates += len(rest)
+					if rest[0].crossSeed {
+						keptCrossSeeds += len(rest)
+					}
+				}
+				log.Info("-----")
+				log.Infof("Free space target of %.2f GB met (%.2f GB), keeping %d remaining candidates",
+					target.GB, c.GetFreeSpace(), keptCanidates)
+				break
+			}
+
+			// members of a group share their data, so only count the largest once
+			var groupBytes int64
+			for _, rc := range group {
+				groupBytes = max(groupBytes, rc.torrent.DownloadedBytes)
+			}
+
+			for _, rc := range group {
+				t := rc.torrent
+				queueRemoval(rc.hash, &t, rc.match, rc.crossSeed, groupBytes)
+				groupBytes = 0
+			}
+		}
+	}
+
+	// check removal limits
+	if err := enforceRemovalLimits(log, result.DryRun, limits, removals, clientTorrents, trackerTorrents); err != nil {
+		result.Ignored = ignoredTorrents
+		result.NonUnique = len(canidates)
+		return err
+	}
 
-		if !noInstances {
-			log.Tracef("%s still not unique unique", t.Name)
+	// helper function to take back the free space a queued removal was expected to free
+	unqueueFreeSpace := func(rc removalCandidate) {
+		if rc.freedBytes > 0 {
+			c.AddFreeSpace(-rc.freedBytes)
+			log.Tracef("Decreased free space by: %s, new free space: %.2f GB",
+				humanize.IBytes(uint64(rc.freedBytes)), c.GetFreeSpace())
+		}
+	}
+
+	// remove queued torrents
+	removedCanidates := 0
+	failedFiles := make(map[string]bool)
+	for _, rc := range removals {
+		t := rc.torrent
+
+		// cross-seeds sharing files with a torrent that failed to be removed must keep their data
+		if rc.crossSeed && slices.ContainsFunc(t.Files, func(f string) bool { return failedFiles[f] }) {
+			log.Warnf("Skipping removal of %q, it shares files with a torrent that failed to be removed", t.Name)
+			unqueueFreeSpace(rc)
 			continue
 		}
 
-		removeTorrent(h, &t)
-		removedCanidates++
+		if !result.DryRun {
+			// do remove, data is kept when recycling
+			removed, err := c.RemoveTorrent(t.Hash, bin == nil)
+			if err == nil && !removed {
+				err = errors.New("torrent was not removed")
+			}
+			if err != nil {
+				log.WithError(err).Errorf("Failed removing torrent: %+v", t)
+				result.addFailure(t.Name, err)
+				for _, f := range t.Files {
+					failedFiles[f] = true
+				}
+				unqueueFreeSpace(rc)
+				errorRemoveTorrents++
+				continue
+			}
+
+			log.Infof("Removed: %q", t.Name)
+
+			// move data to the recycle bin
+			if bin != nil {
+				if moved, err := bin.MoveTorrentFiles(t.Files, t.Path); err != nil {
+					log.WithError(err).Errorf("Failed recycling files of torrent, %d files moved: %q", moved, t.Name)
+					result.addFailure(t.Name, fmt.Errorf("recycle: %w", err))
+					errorRemoveTorrents++
+				} else {
+					log.Infof("Recycled %d files to: %q", moved, bin.Path)
+				}
+			}
+
+			time.Sleep(1 * time.Second)
+		} else {
+			log.Warnf("Dry-run enabled, skipping remove: %q", t.Name)
+		}
+
+		// increased hard removed counters
+		removedTorrentBytes += t.DownloadedBytes
+		hardRemoveTorrents++
+		if rc.crossSeed {
+			removedCanidates++
+		}
+		result.RemovedTorrents = append(result.RemovedTorrents, removedTorrent(&t, rc.match))
 	}
 
 	// show result
@@ -307,5 +735,82 @@ func removeEligibleTorrents(log *logrus.Entry, c client.Interface, torrents map[
 	log.WithField("reclaimed_space", humanize.IBytes(uint64(removedTorrentBytes))).
 		Infof("Removed torrents: %d initially removed, %d cross-seeded torrents were canidates for removal, only %d of them removed and %d failures",
 			hardRemoveTorrents-removedCanidates, len(canidates), removedCanidates, errorRemoveTorrents)
+	if target != nil {
+		log.Infof("Kept torrents: %d candidates kept after meeting the free space target", keptCanidates)
+	}
+
+	result.Ignored = ignoredTorrents
+	result.NonUnique = len(canidates) - removedCanidates - keptCrossSeeds
+	result.Kept = keptCanidates
+	result.Removed = hardRemoveTorrents
+	result.Failures = errorRemoveTorrents
+	result.ReclaimedBytes = uint64(removedTorrentBytes)
 	return nil
 }
+
+// freeSpaceTarget makes clean remove candidates in order of the sort until free space reaches the target.
+type freeSpaceTarget struct {
+	GB   float64
+	Sort *expression.Sort
+}
+
+type removalCandidate struct {
+	hash      string
+	torrent   config.Torrent
+	match     *expression.Match
+	crossSeed bool
+	// bytes added to the free space of the client when queued
+	freedBytes int64
+}
+
+func (rc removalCandidate) Torrent() *config.Torrent {
+	return &rc.torrent
+}
+
+// groupTorrentsByFiles returns the hashes of torrents grouped by the files they share.
+func groupTorrentsByFiles(torrents map[string]config.Torrent) [][]string {
+	hashes := make([]string, 0, len(torrents))
+	for h := range torrents {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	// union torrents sharing a file
+	parent := make(map[string]string, len(hashes))
+	var find func(h string) string
+	find = func(h string) string {
+		if parent[h] != h {
+			parent[h] = find(parent[h])
+		}
+		return parent[h]
+	}
+
+	fileOwner := make(map[string]string)
+	for _, h := range hashes {
+		parent[h] = h
+	}
+	for _, h := range hashes {
+		for _, f := range torrents[h].Files {
+			if owner, ok := fileOwner[f]; ok {
+				parent[find(h)] = find(owner)
+				continue
+			}
+			fileOwner[f] = h
+		}
+	}
+
+	groups := make([][]string, 0)
+	index := make(map[string]int)
+	for _, h := range hashes {
+		root := find(h)
+		i, ok := index[root]
+		if !ok {
+			i = len(groups)
+			index[root] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], h)
+	}
+
+	return groups
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/autobrr/tqm/config"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+func countTorrentsByTracker(torrents map[string]config.Torrent) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range torrents {
+		counts[t.TrackerName]++
+	}
+
+	return counts
+}
+
+// enforceRemovalLimits returns an error when the removals exceed the limits, unless forced or in dry-run.
+func enforceRemovalLimits(log *logrus.Entry, dryRun bool, limits *config.RemovalLimitsConfiguration,
+	removals []removalCandidate, clientTorrents int, trackerTorrents map[string]int) error {
+	if limits == nil {
+		return nil
+	}
+
+	exceeded := checkRemovalLimits(limits, removals, clientTorrents, trackerTorrents)
+	for _, e := range exceeded {
+		log.Errorf("Removal limit exceeded: %s", e)
+	}
+
+	switch {
+	case len(exceeded) == 0:
+	case flagForce:
+		log.Warn("Removal limits exceeded, removing anyway as --force is set")
+	case dryRun:
+		log.Warn("Removal limits exceeded, removals would be aborted unless --force is set")
+	default:
+		return fmt.Errorf("removal limits exceeded, aborted %d removals (use --force to override): %s",
+			len(removals), strings.Join(exceeded, "; "))
+	}
+
+	return nil
+}
+
+// checkRemovalLimits returns the limits exceeded by the removals, out of the torrents of the client and its trackers.
+func checkRemovalLimits(limits *config.RemovalLimitsConfiguration, removals []removalCandidate, clientTorrents int,
+	trackerTorrents map[string]int) []string {
+	exceeded := make([]string, 0)
+
+	// client
+	var (
+		clientBytes    int64
+		trackerRemoved = make(map[string]int)
+		trackerBytes   = make(map[string]int64)
+	)
+	for _, rc := range removals {
+		clientBytes += rc.torrent.DownloadedBytes
+		trackerRemoved[rc.torrent.TrackerName]++
+		trackerBytes[rc.torrent.TrackerName] += rc.torrent.DownloadedBytes
+	}
+
+	exceeded = append(exceeded, checkRemovalLimit("client", &limits.RemovalLimit, len(removals), clientBytes,
+		clientTorrents)...)
+
+	// trackers
+	trackers := make([]string, 0, len(trackerRemoved))
+	for name := range trackerRemoved {
+		trackers = append(trackers, name)
+	}
+	sort.Strings(trackers)
+
+	for _, name := range trackers {
+		limit := limits.ForTracker(name)
+		if limit == nil {
+			continue
+		}
+
+		exceeded = append(exceeded, checkRemovalLimit(fmt.Sprintf("tracker %q", name), limit, trackerRemoved[name],
+			trackerBytes[name], trackerTorrents[name])...)
+	}
+
+	return exceeded
+}
+
+func checkRemovalLimit(scope string, limit *config.RemovalLimit, removed int, bytes int64, total int) []string {
+	exceeded := make([]string, 0)
+
+	if limit.MaxTorrents > 0 && removed > limit.MaxTorrents {
+		exceeded = append(exceeded, fmt.Sprintf("%s: %d torrents exceeds max_torrents of %d", scope, removed,
+			limit.MaxTorrents))
+	}
+
+	if limit.MaxBytes > 0 && uint64(bytes) > uint64(limit.MaxBytes) {
+		exceeded = append(exceeded, fmt.Sprintf("%s: %s exceeds max_bytes of %s", scope,
+			humanize.IBytes(uint64(bytes)), humanize.IBytes(uint64(limit.MaxBytes))))
+	}
+
+	if limit.MaxPercent > 0 && total > 0 {
+		if percent := float64(removed) / float64(total) * 100; percent > limit.MaxPercent {
+			exceeded = append(exceeded, fmt.Sprintf("%s: %.1f%% of %d torrents exceeds max_percent of %.1f%%", scope,
+				percent, total, limit.MaxPercent))
+		}
+	}
+
+	return exceeded
+}
@@ -2,26 +2,34 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/autobrr/tqm/client"
 	"github.com/autobrr/tqm/config"
 	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/notification"
 	paths "github.com/autobrr/tqm/pathutils"
-	"github.com/autobrr/tqm/torrentfilemap"
 	"github.com/autobrr/tqm/tracker"
 
 	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var orphanCmd = &cobra.Command{
-	Use:   "orphan [CLIENT]",
+	Use:   "orphan [CLIENT | --all]",
 	Short: "Check download location for orphan files/folders not in torrent client",
 	Long:  `This command can be used to find files and folders in the download_location that are no longer in the torrent client.`,
 
-	Args: cobra.ExactArgs(1),
+	Args: clientArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// init core
 		if !initialized {
@@ -32,176 +40,448 @@ var orphanCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("orphan")
 
-		// retrieve client object
-		clientName := args[0]
-		clientConfig, ok := config.Config.Clients[clientName]
-		if !ok {
-			log.Fatalf("No client configuration found for: %q", clientName)
-		}
-
-		// validate client is enabled
-		if err := validateClientEnabled(clientConfig); err != nil {
-			log.WithError(err).Fatal("Failed validating client is enabled")
+		if flagAll {
+			if err := runCommandAll(log, "orphan"); err != nil {
+				log.WithError(err).Fatal("Failed removing orphans of clients")
+			}
+			return
 		}
 
-		// retrieve client type
-		clientType, err := getClientConfigString("type", clientConfig)
+		// remove client orphans
+		result, err := runCommand("orphan", args[0], false)
+		writeMetricsTextfile(log, result)
 		if err != nil {
-			log.WithError(err).Fatal("Failed determining client type")
+			log.WithError(err).Fatal("Failed removing orphans of client")
 		}
+	},
+}
 
-		// retrieve client download path
-		clientDownloadPath, err := getClientConfigString("download_path", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client download path")
-		} else if clientDownloadPath == nil || *clientDownloadPath == "" {
-			log.Fatal("Client download path must be set...")
-		}
+func init() {
+	rootCmd.AddCommand(orphanCmd)
 
-		// retrieve client download path mapping
-		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed loading client download path mappings")
-		} else if clientDownloadPathMapping != nil {
-			log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
-				clientDownloadPathMapping)
+	orphanCmd.Flags().BoolVar(&flagAll, "all", false, "Run against all enabled clients")
+}
+
+func runOrphan(log *logrus.Entry, clientName string, result *runResult) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client orphan settings
+	clientOrphanSettings, err := getClientOrphanSettings(clientName, clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client orphan settings: %w", err)
+	}
+
+	exclusions, err := newOrphanExclusions(clientOrphanSettings)
+	if err != nil {
+		return fmt.Errorf("compile client orphan settings: %w", err)
+	}
+
+	// retrieve client recycle bin
+	clientRecycleBin, err := getClientRecycleBin(clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client recycle bin: %w", err)
+	} else if clientRecycleBin != nil {
+		log.Infof("Recycling orphan files to: %q", clientRecycleBin.Path)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, nil)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	result.Torrents = len(torrents)
+
+	if flagLogLevel > 1 {
+		if b, err := json.Marshal(torrents); err != nil {
+			log.WithError(err).Error("Failed marshalling torrents")
+		} else {
+			log.Trace(string(b))
 		}
+	}
+
+	// retrieve client download paths
+	roots, idx, err := getClientOrphanRoots(log, c, clientName, clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client download paths: %w", err)
+	}
+
+	// retrieve torrents of clients sharing data with the client, their files are never orphans
+	sharedTorrents, err := loadSharedTorrents(log, clientName, clientConfig)
+	if err != nil {
+		return fmt.Errorf("load shared torrents: %w", err)
+	}
+
+	// create index of local torrent files and their folders
+	for _, t := range torrents {
+		idx.AddTorrent(t)
+	}
+	for _, t := range sharedTorrents {
+		idx.AddTorrent(t)
+	}
+	log.Infof("Indexed torrents to %d unique torrent files in %d folders", idx.Files(), idx.Dirs())
+
+	// sort paths into their respective maps
+	localFilePaths := make(map[string]int64)
+	localFolderPaths := make(map[string]int64)
+	localPathRoots := make(map[string]*orphanRoot)
+	skipped := make(map[string]int)
+
+	for _, root := range roots {
+		// get all paths in download location
+		localDownloadPaths, _ := paths.GetPathsInFolder(root, true, true, nil)
+		log.Tracef("Retrieved %d paths from: %q", len(localDownloadPaths), root)
+
+		rootResult := &orphanRoot{Path: root}
+		result.OrphanRoots = append(result.OrphanRoots, rootResult)
+
+		files, folders := 0, 0
+		for _, p := range localDownloadPaths {
+			if clientRecycleBin != nil && paths.IsWithin(p.RealPath, clientRecycleBin.Path) {
+				// ignore recycled paths
+				continue
+			}
 
-		// load client object
-		c, err := client.NewClient(*clientType, clientName, nil)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+			if p.IsDir && strings.EqualFold(p.RealPath, root) {
+				// ignore root download path
+				continue
+			}
+
+			// paths of torrents are never orphans, there is no need to check whether to skip them
+			indexed := (p.IsDir && idx.HasDir(p.RealPath)) || (!p.IsDir && idx.HasFile(p.RealPath))
+			if !indexed {
+				if reason := exclusions.skipReason(root, p); reason != "" {
+					// keep the path and the folders containing it
+					log.Debugf("Skipping orphan (%s): %q", reason, p.RealPath)
+					if p.IsDir {
+						idx.AddDir(p.RealPath)
+					} else {
+						idx.AddFile(p.RealPath)
+					}
+
+					skipped[reason]++
+					continue
+				}
+			}
+
+			if p.IsDir {
+				localFolderPaths[p.RealPath] = p.Size
+				folders++
+			} else {
+				localFilePaths[p.RealPath] = p.Size
+				files++
+			}
+
+			localPathRoots[p.RealPath] = rootResult
 		}
 
-		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+		log.Infof("Retrieved paths from %q: %d files / %d folders", root, files, folders)
+	}
+
+	// remove local files not associated with a torrent
+	removeFailures := 0
+	removedLocalFiles := 0
+	var removedLocalFilesSize uint64 = 0
 
-		// connect to client
-		if err := c.Connect(); err != nil {
-			log.WithError(err).Fatal("Failed connecting")
+	// paths removed (or that would have been removed during a dry-run), to find folders left empty
+	removedPaths := make(map[string]bool)
+
+	for localPath, localPathSize := range localFilePaths {
+		if idx.HasFile(localPath) {
+			continue
 		} else {
-			log.Debugf("Connected to client")
+			log.Info("-----")
+
+			// file is not associated with a torrent
+			removed := true
+
+			log.Infof("Removing orphan: %q", localPath)
+			if result.DryRun {
+				log.Warn("Dry-run enabled, skipping remove...")
+			} else if clientRecycleBin != nil {
+				// move file to the recycle bin
+				if dst, err := clientRecycleBin.Move(localPath); err != nil {
+					log.WithError(err).Errorf("Failed recycling orphan...")
+					result.addFailure(localPath, err)
+					removeFailures++
+					removed = false
+				} else {
+					log.Infof("Recycled to: %q", dst)
+				}
+			} else {
+				// remove file
+				if err := os.Remove(localPath); err != nil {
+					log.WithError(err).Errorf("Failed removing orphan...")
+					result.addFailure(localPath, err)
+					removeFailures++
+					removed = false
+				} else {
+					log.Info("Removed")
+				}
+			}
+
+			if removed {
+				notifyEvent(log, result, notification.Event{
+					Name:  localPath,
+					Bytes: localPathSize,
+				})
+
+				removedPaths[localPath] = true
+				removedLocalFilesSize += uint64(localPathSize)
+				removedLocalFiles++
+				localPathRoots[localPath].Files++
+				localPathRoots[localPath].Bytes += uint64(localPathSize)
+			}
 		}
+	}
+
+	// remove local folders not associated with a torrent, deepest first so folders left empty by the removal of
+	// their sub folders are removed as well
+	removedLocalFolders := 0
+	nonEmptyLocalFolders := 0
 
-		// retrieve torrents
-		torrents, err := c.GetTorrents()
+	orphanFolders := make([]string, 0, len(localFolderPaths))
+	for localPath := range localFolderPaths {
+		if !idx.HasDir(localPath) {
+			orphanFolders = append(orphanFolders, localPath)
+		}
+	}
+	sortDeepestFirst(orphanFolders)
+
+	for _, localPath := range orphanFolders {
+		// folders still containing paths, e.g. files that failed to be removed, are kept
+		empty, err := isEmptyFolder(localPath, removedPaths)
 		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving torrents")
-		} else {
-			log.Infof("Retrieved %d torrents", len(torrents))
+			log.WithError(err).Errorf("Failed reading orphan folder: %q", localPath)
+			result.addFailure(localPath, err)
+			removeFailures++
+			continue
+		} else if !empty {
+			log.Debugf("Skipping non-empty orphan folder: %q", localPath)
+			nonEmptyLocalFolders++
+			continue
 		}
 
-		if flagLogLevel > 1 {
-			if b, err := json.Marshal(torrents); err != nil {
-				log.WithError(err).Error("Failed marshalling torrents")
+		log.Info("-----")
+
+		// folder is not associated with a torrent
+		removed := true
+
+		log.Infof("Removing orphan: %q", localPath)
+		if result.DryRun {
+			log.Warn("Dry-run enabled, skipping remove...")
+		} else {
+			// remove folder
+			if err := os.Remove(localPath); err != nil {
+				log.WithError(err).Errorf("Failed removing orphan...")
+				result.addFailure(localPath, err)
+				removeFailures++
+				removed = false
 			} else {
-				log.Trace(string(b))
+				log.Info("Removed")
 			}
 		}
 
-		// create map of files associated to torrents (via hash)
-		tfm := torrentfilemap.New(torrents)
-		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
+		if removed {
+			notifyEvent(log, result, notification.Event{
+				Name: localPath,
+			})
 
-		// get all paths in client download location
-		localDownloadPaths, _ := paths.GetPathsInFolder(*clientDownloadPath, true, true,
-			nil)
-		log.Tracef("Retrieved %d paths from: %q", len(localDownloadPaths), *clientDownloadPath)
+			removedPaths[localPath] = true
+			removedLocalFolders++
+			localPathRoots[localPath].Folders++
+		}
+	}
 
-		// sort paths into their respective maps
-		localFilePaths := make(map[string]int64)
-		localFolderPaths := make(map[string]int64)
+	log.Info("-----")
+	if len(roots) > 1 {
+		for _, r := range result.OrphanRoots {
+			log.WithField("reclaimed_space", humanize.IBytes(r.Bytes)).
+				Infof("Removed orphans from %q: %d files, %d folders", r.Path, r.Files, r.Folders)
+		}
+	}
 
-		for _, p := range localDownloadPaths {
-			p := p
-			if p.IsDir {
-				if strings.EqualFold(p.RealPath, *clientDownloadPath) {
-					// ignore root download path
-					continue
-				}
+	log.WithField("reclaimed_space", humanize.IBytes(removedLocalFilesSize)).
+		Infof("Removed orphans: %d files, %d folders and %d failures",
+			removedLocalFiles, removedLocalFolders, removeFailures)
 
-				localFolderPaths[p.RealPath] = p.Size
-			} else {
-				localFilePaths[p.RealPath] = p.Size
-			}
+	if nonEmptyLocalFolders > 0 {
+		log.Infof("Skipped non-empty orphan folders: %d", nonEmptyLocalFolders)
+	}
+
+	if len(skipped) > 0 {
+		log.Infof("Skipped orphans: %s", skippedString(skipped))
+	}
+
+	result.OrphansSkipped = skipped
+	result.OrphanFiles = removedLocalFiles
+	result.OrphanFolders = removedLocalFolders
+	result.OrphanFoldersNonEmpty = nonEmptyLocalFolders
+	result.Failures = removeFailures
+	result.ReclaimedBytes = removedLocalFilesSize
+
+	return nil
+}
+
+// sortDeepestFirst sorts folders by depth, deepest first, then by path.
+func sortDeepestFirst(dirs []string) {
+	sort.Slice(dirs, func(i, j int) bool {
+		di := strings.Count(dirs[i], string(filepath.Separator))
+		dj := strings.Count(dirs[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
 		}
+		return dirs[i] < dirs[j]
+	})
+}
 
-		log.Infof("Retrieved paths from %q: %d files / %d folders", *clientDownloadPath, len(localFilePaths),
-			len(localFolderPaths))
+// isEmptyFolder returns whether a folder contains nothing but removed paths.
+func isEmptyFolder(path string, removed map[string]bool) (bool, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false, fmt.Errorf("read directory: %w", err)
+	}
 
-		// remove local files not associated with a torrent
-		removeFailures := 0
-		removedLocalFiles := 0
-		var removedLocalFilesSize uint64 = 0
+	for _, e := range entries {
+		if !removed[filepath.Join(path, e.Name())] {
+			return false, nil
+		}
+	}
 
-		for localPath, localPathSize := range localFilePaths {
-			if tfm.HasPath(localPath, clientDownloadPathMapping) {
-				continue
-			} else {
-				log.Info("-----")
+	return true, nil
+}
 
-				// file is not associated with a torrent
-				removed := true
+// orphan paths are skipped for these reasons
+const (
+	skipExcluded         = "excluded"
+	skipIgnoredExtension = "ignored extension"
+	skipTooRecent        = "too recent"
+)
 
-				log.Infof("Removing orphan: %q", localPath)
-				if flagDryRun {
-					log.Warn("Dry-run enabled, skipping remove...")
-				} else {
-					// remove file
-					if err := os.Remove(localPath); err != nil {
-						log.WithError(err).Errorf("Failed removing orphan...")
-						removeFailures++
-						removed = false
-					} else {
-						log.Info("Removed")
-					}
-				}
+// orphanExclusions decides which local paths are kept by orphan, regardless of the torrents of the client.
+type orphanExclusions struct {
+	minAge     time.Duration
+	globs      []string
+	regexes    []*regexp.Regexp
+	extensions []string
+	now        time.Time
+}
 
-				if removed {
-					removedLocalFilesSize += uint64(localPathSize)
-					removedLocalFiles++
-				}
-			}
+func newOrphanExclusions(settings *config.OrphanConfiguration) (*orphanExclusions, error) {
+	e := &orphanExclusions{
+		minAge: settings.MinAge,
+		globs:  settings.Exclude,
+		now:    time.Now(),
+	}
+
+	for _, g := range settings.Exclude {
+		if _, err := filepath.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("exclude: %q: %w", g, err)
 		}
+	}
 
-		// remove local folders not associated with a torrent
-		removedLocalFolders := 0
+	for _, r := range settings.ExcludeRegex {
+		re, err := regexp.Compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("exclude_regex: %q: %w", r, err)
+		}
 
-		for localPath := range localFolderPaths {
-			if tfm.HasPath(localPath, clientDownloadPathMapping) {
-				continue
-			} else {
-				log.Info("-----")
+		e.regexes = append(e.regexes, re)
+	}
 
-				// folder is not associated with a torrent
-				removed := true
+	for _, ext := range settings.IgnoreExtensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
 
-				log.Infof("Removing orphan: %q", localPath)
-				if flagDryRun {
-					log.Warn("Dry-run enabled, skipping remove...")
-				} else {
-					// remove folder
-					if err := os.Remove(localPath); err != nil {
-						log.WithError(err).Errorf("Failed removing orphan...")
-						removeFailures++
-						removed = false
-					} else {
-						log.Info("Removed")
-					}
-				}
+		e.extensions = append(e.extensions, ext)
+	}
 
-				if removed {
-					removedLocalFolders++
-				}
+	return e, nil
+}
+
+// skipReason returns why a local path within a download path is kept, empty when it may be removed.
+func (e *orphanExclusions) skipReason(root string, p paths.Path) string {
+	rel, err := filepath.Rel(root, p.RealPath)
+	if err != nil {
+		rel = p.RealPath
+	}
+	rel = filepath.ToSlash(rel)
+
+	// globs match the relative path or any of its names, so the contents of excluded folders are excluded too
+	names := strings.Split(rel, "/")
+	for _, g := range e.globs {
+		if ok, _ := path.Match(g, rel); ok {
+			return skipExcluded
+		}
+
+		for _, name := range names {
+			if ok, _ := path.Match(g, name); ok {
+				return skipExcluded
 			}
 		}
+	}
 
-		log.Info("-----")
-		log.WithField("reclaimed_space", humanize.IBytes(removedLocalFilesSize)).
-			Infof("Removed orphans: %d files, %d folders and %d failures",
-				removedLocalFiles, removedLocalFolders, removeFailures)
-	},
+	// regexes match the relative path or that of any folder containing it, the same as globs
+	for _, re := range e.regexes {
+		for i := range names {
+			if re.MatchString(strings.Join(names[:i+1], "/")) {
+				return skipExcluded
+			}
+		}
+	}
+
+	if !p.IsDir && slices.Contains(e.extensions, strings.ToLower(filepath.Ext(p.RealPath))) {
+		return skipIgnoredExtension
+	}
+
+	if e.minAge > 0 && e.now.Sub(p.ModifiedTime) < e.minAge {
+		return skipTooRecent
+	}
+
+	return ""
 }
 
-func init() {
-	rootCmd.AddCommand(orphanCmd)
+func skippedString(skipped map[string]int) string {
+	parts := make([]string, 0, len(skipped))
+	for _, reason := range []string{skipExcluded, skipIgnoredExtension, skipTooRecent} {
+		if n, ok := skipped[reason]; ok {
+			parts = append(parts, fmt.Sprintf("%d %s", n, reason))
+		}
+	}
+
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+)
+
+const orphanTestConfig = `
+clients:
+  snapshot:
+    enabled: true
+    type: snapshot
+    path: %[1]s/client.json
+    filter: default
+    download_path: %[2]s
+    download_path_mapping:
+      /downloads: %[2]s
+    share_data_with:
+      - shared
+    orphan:
+      exclude_regex:
+        - ^Manual$
+  shared:
+    enabled: false
+    type: snapshot
+    path: %[1]s/shared.json
+    filter: default
+    download_path_mapping:
+      /data: %[2]s
+filters:
+  default:
+    ignore:
+      - Downloaded == false
+`
+
+// setupOrphanConfig loads a config with a snapshot client seeding from a download folder created with files,
+// along with a shared client seeding from the same folder.
+func setupOrphanConfig(t *testing.T, files []string, torrents map[string][]string, shared map[string][]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	downloads := filepath.Join(dir, "downloads")
+	for _, f := range files {
+		p := filepath.Join(downloads, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("create folder: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	writeSnapshot := func(name string, torrentFiles map[string][]string) {
+		s := client.SnapshotFile{Torrents: make(map[string]config.Torrent)}
+		for h, files := range torrentFiles {
+			s.Torrents[h] = config.Torrent{Hash: h, Name: h, Files: files, Downloaded: true}
+		}
+
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatalf("marshal snapshot: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatalf("write snapshot: %v", err)
+		}
+	}
+	writeSnapshot("client.json", torrents)
+	writeSnapshot("shared.json", shared)
+
+	loadTestConfig(t, dir, fmt.Sprintf(orphanTestConfig, dir, downloads))
+
+	return downloads
+}
+
+// remainingFiles returns the files left within a folder, relative to it.
+func remainingFiles(t *testing.T, root string) []string {
+	t.Helper()
+
+	files := make([]string, 0)
+	if err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, p)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+
+	slices.Sort(files)
+	return files
+}
+
+func TestOrphanSharedClient(t *testing.T) {
+	downloads := setupOrphanConfig(t,
+		[]string{"Own.Torrent/file.mkv", "Shared.Torrent/file.mkv", "Orphan.Torrent/file.mkv"},
+		map[string][]string{"OWN": {"/downloads/Own.Torrent/file.mkv"}},
+		map[string][]string{"SHARED": {"/data/Shared.Torrent/file.mkv"}})
+
+	result, err := runCommand("orphan", "snapshot", false)
+	if err != nil {
+		t.Fatalf("run orphan: %v", err)
+	}
+
+	// files seeded by the shared client are not orphans
+	if want := []string{"Own.Torrent/file.mkv", "Shared.Torrent/file.mkv"}; !slices.Equal(remainingFiles(t, downloads), want) {
+		t.Errorf("expected %v to remain, got %v", want, remainingFiles(t, downloads))
+	}
+	if result.OrphanFiles != 1 || result.OrphanFolders != 1 {
+		t.Errorf("expected 1 orphan file and folder, got %d and %d", result.OrphanFiles, result.OrphanFolders)
+	}
+}
+
+func TestOrphanExcludedFolder(t *testing.T) {
+	downloads := setupOrphanConfig(t,
+		[]string{"Manual/file.mkv", "Manual/Sub/file.nfo", "Manual.Torrent/file.mkv"},
+		map[string][]string{}, map[string][]string{})
+
+	result, err := runCommand("orphan", "snapshot", false)
+	if err != nil {
+		t.Fatalf("run orphan: %v", err)
+	}
+
+	// the contents of a folder matched by a regex are excluded too
+	if want := []string{"Manual/Sub/file.nfo", "Manual/file.mkv"}; !slices.Equal(remainingFiles(t, downloads), want) {
+		t.Errorf("expected %v to remain, got %v", want, remainingFiles(t, downloads))
+	}
+	if result.OrphanFiles != 1 {
+		t.Errorf("expected 1 orphan file, got %d", result.OrphanFiles)
+	}
+}
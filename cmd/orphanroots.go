@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/pathindex"
+	paths "github.com/autobrr/tqm/pathutils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// orphanRoot is a local folder scanned for orphans, with the orphans removed from it.
+type orphanRoot struct {
+	Path    string `json:"path"`
+	Files   int    `json:"files"`
+	Folders int    `json:"folders"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// getClientOrphanRoots returns the local folders to scan for orphans of a client, from download_path,
+// download_paths and, when download_paths_from_labels is set, the label paths and default save path of the client.
+// Folders within other folders are scanned along with them. The index returned maps client paths into each folder
+// of download_paths with its own mapping (on top of download_path_mapping), other paths with download_path_mapping.
+func getClientOrphanRoots(log *logrus.Entry, c client.Interface, clientName string,
+	clientConfig map[string]interface{}) ([]string, *pathindex.Index, error) {
+	clientPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load client download path mappings: %w", err)
+	} else if len(clientPathMapping) > 0 {
+		log.Debugf("Loaded %d client download path mappings: %#v", len(clientPathMapping), clientPathMapping)
+	}
+
+	idx := pathindex.New(nil, clientPathMapping)
+	candidates := make([]string, 0)
+	if downloadPath, err := getClientConfigString("download_path", clientConfig); err == nil && *downloadPath != "" {
+		candidates = append(candidates, *downloadPath)
+	}
+
+	// configured download paths
+	if _, ok := clientConfig["download_paths"]; ok {
+		var downloadPaths []config.DownloadPathConfiguration
+		if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%sdownload_paths", config.Delimiter, clientName,
+			config.Delimiter), &downloadPaths); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal download_paths of client: %w", err)
+		}
+
+		for _, d := range downloadPaths {
+			if d.Path == "" {
+				return nil, nil, errors.New("download_paths must not contain empty paths")
+			}
+
+			candidates = append(candidates, d.Path)
+
+			// the folder's own mapping takes precedence within it
+			pathMapping := maps.Clone(clientPathMapping)
+			if pathMapping == nil {
+				pathMapping = make(map[string]string)
+			}
+			maps.Copy(pathMapping, d.Mapping)
+			idx.AddRoot(d.Path, pathMapping)
+			log.Debugf("Loaded %d download path mappings for %q: %#v", len(pathMapping), d.Path, pathMapping)
+		}
+	}
+
+	// download paths of the client
+	if v, ok := clientConfig["download_paths_from_labels"]; ok {
+		fromLabels, ok := v.(bool)
+		if !ok {
+			return nil, nil, fmt.Errorf("failed type-asserting download_paths_from_labels of client: %#v", v)
+		}
+
+		if fromLabels {
+			if err := c.LoadLabelPathMap(); err != nil {
+				return nil, nil, fmt.Errorf("load label path map: %w", err)
+			}
+
+			for _, p := range c.LabelPathMap() {
+				candidates = append(candidates, idx.LocalPath(p))
+			}
+
+			if sc, ok := c.(client.SavePathInterface); ok && sc.DefaultSavePath() != "" {
+				candidates = append(candidates, idx.LocalPath(sc.DefaultSavePath()))
+			}
+		}
+	}
+
+	// shortest paths first, so nested folders are dropped
+	for i, p := range candidates {
+		candidates[i] = filepath.Clean(p)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i]) != len(candidates[j]) {
+			return len(candidates[i]) < len(candidates[j])
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	roots := make([]string, 0, len(candidates))
+	for _, p := range candidates {
+		nested := false
+		for _, r := range roots {
+			if paths.IsWithin(p, r) {
+				nested = true
+				break
+			}
+		}
+		if nested {
+			continue
+		}
+
+		if _, err := os.Stat(p); err != nil {
+			log.WithError(err).Warnf("Skipping download path: %q", p)
+			continue
+		}
+
+		roots = append(roots, p)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, errors.New("client download path must be set")
+	} else if len(roots) == 0 {
+		return nil, nil, errors.New("none of the client download paths exist")
+	}
+
+	sort.Strings(roots)
+	return roots, idx, nil
+}
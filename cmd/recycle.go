@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/logger"
+	paths "github.com/autobrr/tqm/pathutils"
+	"github.com/autobrr/tqm/recycle"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const defaultRecycleRetentionDays = 7
+
+var (
+	flagRecycleRetentionDays int
+)
+
+var recycleCmd = &cobra.Command{
+	Use:   "recycle",
+	Short: "Work with the recycle bin of a client",
+	Long:  `This command can be used to work with the recycle_path of a client, where clean and orphan move removed data to.`,
+}
+
+var recyclePurgeCmd = &cobra.Command{
+	Use:   "purge [CLIENT]",
+	Short: "Delete recycled data older than the retention period",
+	Long:  `This command can be used to permanently delete the dated folders of a recycle bin older than the retention period.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("recycle")
+
+		// purge recycle bin
+		if err := runRecyclePurge(log, args[0], cmd.Flags().Changed("retention-days")); err != nil {
+			log.WithError(err).Fatal("Failed purging recycle bin")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recycleCmd)
+	recycleCmd.AddCommand(recyclePurgeCmd)
+
+	recyclePurgeCmd.Flags().IntVar(&flagRecycleRetentionDays, "retention-days", defaultRecycleRetentionDays,
+		"Days to keep recycled data for, instead of the recycle_retention_days of the client")
+}
+
+func runRecyclePurge(log *logrus.Entry, clientName string, retentionSet bool) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// retrieve client recycle bin
+	bin, err := getClientRecycleBin(clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client recycle bin: %w", err)
+	} else if bin == nil {
+		return errors.New("client has no recycle_path")
+	}
+
+	// retrieve retention
+	retentionDays := flagRecycleRetentionDays
+	if v, ok := clientConfig["recycle_retention_days"]; ok && !retentionSet {
+		days, ok := v.(int)
+		if !ok {
+			return fmt.Errorf("failed type-asserting recycle_retention_days of client: %#v", v)
+		}
+
+		retentionDays = days
+	}
+
+	if retentionDays < 0 {
+		return fmt.Errorf("retention days must not be negative: %d", retentionDays)
+	}
+
+	// folders dated before the cutoff are purged
+	now := time.Now()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -retentionDays)
+	log.Infof("Purging recycled data of %q from before %s (%d days)", bin.Path, cutoff.Format(recycle.DateLayout),
+		retentionDays)
+
+	entries, err := bin.Entries()
+	if err != nil {
+		return fmt.Errorf("retrieve recycle bin entries: %w", err)
+	}
+
+	purged := 0
+	failures := 0
+	var purgedSize uint64 = 0
+
+	for _, e := range entries {
+		if !e.Date.Before(cutoff) {
+			continue
+		}
+
+		_, size := paths.GetPathsInFolder(e.Path, true, false, nil)
+
+		log.Info("-----")
+		log.Infof("Purging: %q - %s", e.Path, humanize.IBytes(size))
+		if flagDryRun {
+			log.Warn("Dry-run enabled, skipping purge...")
+		} else if err := os.RemoveAll(e.Path); err != nil {
+			log.WithError(err).Error("Failed purging...")
+			failures++
+			continue
+		} else {
+			log.Info("Purged")
+		}
+
+		purged++
+		purgedSize += size
+	}
+
+	log.Info("-----")
+	log.WithField("reclaimed_space", humanize.IBytes(purgedSize)).
+		Infof("Purged %d of %d recycle bin folders and %d failures", purged, len(entries), failures)
+
+	if failures > 0 {
+		return fmt.Errorf("failed purging %d folders", failures)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/autobrr/tqm/client"
@@ -14,15 +15,16 @@ import (
 	"github.com/autobrr/tqm/tracker"
 
 	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var relabelCmd = &cobra.Command{
-	Use:   "relabel [CLIENT]",
+	Use:   "relabel [CLIENT | --all]",
 	Short: "Check torrent client for torrents to relabel",
 	Long:  `This command can be used to check a torrent clients queue for torrents to relabel based on its configured filters.`,
 
-	Args: cobra.ExactArgs(1),
+	Args: clientArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// init core
 		if !initialized {
@@ -33,131 +35,168 @@ var relabelCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("relabel")
 
-		// retrieve client object
-		clientName := args[0]
-		clientConfig, ok := config.Config.Clients[clientName]
-		if !ok {
-			log.Fatalf("No client configuration found for: %q", clientName)
-		}
-
-		// validate client is enabled
-		if err := validateClientEnabled(clientConfig); err != nil {
-			log.WithError(err).Fatal("Failed validating client is enabled")
-		}
-
-		// retrieve client type
-		clientType, err := getClientConfigString("type", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client type")
+		if flagAll {
+			if err := runCommandAll(log, "relabel"); err != nil {
+				log.WithError(err).Fatal("Failed relabeling clients")
+			}
+			return
 		}
 
-		// retrieve client free space path
-		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
-
-		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
+		// relabel client
+		// actions are only planned when writing a plan
+		result, err := runCommand("relabel", args[0], flagPlanOut != "")
+		writeMetricsTextfile(log, result)
 		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving client filter")
+			log.WithError(err).Fatal("Failed relabeling client")
 		}
 
-		if flagFilterName != "" {
-			clientFilter, err = getFilter(flagFilterName)
-			if err != nil {
-				log.WithError(err).Fatal("Failed retrieving specified filter")
+		if flagPlanOut != "" {
+			if err := writePlan(log, result, flagPlanOut); err != nil {
+				log.WithError(err).Fatal("Failed writing plan")
 			}
 		}
+	},
+}
 
-		// compile client filters
-		exp, err := expression.Compile(clientFilter)
-		if err != nil {
-			log.WithError(err).Fatal("Failed compiling client filters")
-		}
+func init() {
+	rootCmd.AddCommand(relabelCmd)
+
+	relabelCmd.Flags().BoolVar(&flagAll, "all", false, "Run against all enabled clients")
+	relabelCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	relabelCmd.Flags().StringVar(&flagPlanOut, "plan-out", "", "Write planned actions to file instead of executing them")
+}
 
-		// load client object
-		c, err := client.NewClient(*clientType, clientName, exp)
+func runRelabel(log *logrus.Entry, clientName string, result *runResult) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// retrieve client filters
+	clientFilter, err := getClientFilter(clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client filter: %w", err)
+	}
+
+	if flagFilterName != "" {
+		clientFilter, err = getFilter(flagFilterName)
 		if err != nil {
-			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+			return fmt.Errorf("retrieve specified filter: %w", err)
 		}
-
-		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
-
-		// connect to client
-		if err := c.Connect(); err != nil {
-			log.WithError(err).Fatal("Failed connecting")
+	}
+
+	// compile client filters
+	exp, err := expression.Compile(clientFilter)
+	if err != nil {
+		return fmt.Errorf("compile client filters: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// get free disk space (can/will be used by filters)
+	if clientFreeSpacePath != nil {
+		space, err := c.GetCurrentFreeSpace(*clientFreeSpacePath)
+		if err != nil {
+			log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
 		} else {
-			log.Debugf("Connected to client")
-		}
+			log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
+				humanize.IBytes(uint64(space)), c.GetFreeSpace())
 
-		// get free disk space (can/will be used by filters)
-		if clientFreeSpacePath != nil {
-			space, err := c.GetCurrentFreeSpace(*clientFreeSpacePath)
-			if err != nil {
-				log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
-			} else {
-				log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
-					humanize.IBytes(uint64(space)), c.GetFreeSpace())
-			}
-		}
-
-		// load client label path map
-		if err := c.LoadLabelPathMap(); err != nil {
-			log.WithError(err).Fatal("Failed loading label path map")
+			freeSpaceGB := c.GetFreeSpace()
+			result.FreeSpaceGB = &freeSpaceGB
 		}
-
-		// retrieve torrents
-		torrents, err := c.GetTorrents()
-		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving torrents")
+	}
+
+	// load client label path map
+	if err := c.LoadLabelPathMap(); err != nil {
+		return fmt.Errorf("load label path map: %w", err)
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	result.Torrents = len(torrents)
+
+	// set fields tracked across runs
+	if err := setTorrentState(log, clientName, torrents, !result.DryRun,
+		tracksUnregistered(exp, nil)); err != nil {
+		return fmt.Errorf("set torrent state: %w", err)
+	}
+
+	if flagLogLevel > 1 {
+		if b, err := json.Marshal(torrents); err != nil {
+			log.WithError(err).Error("Failed marshalling torrents")
 		} else {
-			log.Infof("Retrieved %d torrents", len(torrents))
+			log.Trace(string(b))
 		}
+	}
 
-		if flagLogLevel > 1 {
-			if b, err := json.Marshal(torrents); err != nil {
-				log.WithError(err).Error("Failed marshalling torrents")
-			} else {
-				log.Trace(string(b))
-			}
-		}
-
-		// create map of files associated to torrents (via hash)
-		tfm := torrentfilemap.New(torrents)
-		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
-
-		if sliceutils.StringSliceContains(clientFilter.MapHardlinksFor, "relabel", true) {
-			// download path mapping
-			clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
-			if err != nil {
-				log.WithError(err).Fatal("Failed loading client download path mappings")
-			} else if clientDownloadPathMapping != nil {
-				log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
-					clientDownloadPathMapping)
-			}
-
-			// create map of paths associated to underlying file ids
-			start := time.Now()
-			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
-			log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+	// create map of files associated to torrents (via hash)
+	tfm := torrentfilemap.New(torrents)
+	log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
 
-			// add HardlinkedOutsideClient field to torrents
-			for h, t := range torrents {
-				t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
-				torrents[h] = t
-			}
-		} else {
-			log.Warnf("Not mapping hardlinks for client %q", clientName)
-			log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'relabel' to the 'MapHardlinksFor' field in your filter configuration")
+	if sliceutils.StringSliceContains(clientFilter.MapHardlinksFor, "relabel", true) {
+		// download path mapping
+		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+		if err != nil {
+			return fmt.Errorf("load client download path mappings: %w", err)
+		} else if clientDownloadPathMapping != nil {
+			log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
+				clientDownloadPathMapping)
 		}
 
-		// relabel torrents that meet the filter criteria
-		if err := relabelEligibleTorrents(log, c, torrents, tfm); err != nil {
-			log.WithError(err).Fatal("Failed relabeling eligible torrents...")
+		// create map of paths associated to underlying file ids
+		start := time.Now()
+		hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
+		log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+
+		// add HardlinkedOutsideClient field to torrents
+		for h, t := range torrents {
+			t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
+			torrents[h] = t
 		}
-	},
-}
+	} else {
+		log.Warnf("Not mapping hardlinks for client %q", clientName)
+		log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'relabel' to the 'MapHardlinksFor' field in your filter configuration")
+	}
 
-func init() {
-	rootCmd.AddCommand(relabelCmd)
+	// relabel torrents that meet the filter criteria
+	if err := relabelEligibleTorrents(log, c, torrents, tfm, result, exp.OrderBy); err != nil {
+		return fmt.Errorf("relabel eligible torrents: %w", err)
+	}
 
-	relabelCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	return nil
 }
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/autobrr/tqm/client"
@@ -13,15 +15,16 @@ import (
 	"github.com/autobrr/tqm/tracker"
 
 	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var retagCmd = &cobra.Command{
-	Use:   "retag [CLIENT]",
-	Short: "Check client (only qbit) for torrents to retag",
+	Use:   "retag [CLIENT | --all]",
+	Short: "Check client (only qbit and transmission) for torrents to retag",
 	Long:  `This command can be used to check a torrent clients queue for torrents to retag based on its configured filters.`,
 
-	Args: cobra.ExactArgs(1),
+	Args: clientArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// init core
 		if !initialized {
@@ -32,143 +35,175 @@ var retagCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("retag")
 
-		// retrieve client object
-		clientName := args[0]
-		clientConfig, ok := config.Config.Clients[clientName]
-		if !ok {
-			log.Fatalf("No client configuration found for: %q", clientName)
-		}
-
-		// validate client is enabled
-		if err := validateClientEnabled(clientConfig); err != nil {
-			log.WithError(err).Fatal("Failed validating client is enabled")
-		}
-
-		// retrieve client type
-		clientType, err := getClientConfigString("type", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client type")
-		}
-
-		if *clientType != "qbittorrent" {
-			log.Fatalf("Retagging is currently only supported for qbittorrent")
+		if flagAll {
+			if err := runCommandAll(log, "retag"); err != nil {
+				log.WithError(err).Fatal("Failed retagging clients")
+			}
+			return
 		}
 
-		// retrieve client free space path
-		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
-
-		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
+		// retag client
+		// actions are only planned when writing a plan
+		result, err := runCommand("retag", args[0], flagPlanOut != "")
+		writeMetricsTextfile(log, result)
 		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving client filter")
+			log.WithError(err).Fatal("Failed retagging client")
 		}
 
-		if flagFilterName != "" {
-			clientFilter, err = getFilter(flagFilterName)
-			if err != nil {
-				log.WithError(err).Fatal("Failed retrieving specified filter")
+		if flagPlanOut != "" {
+			if err := writePlan(log, result, flagPlanOut); err != nil {
+				log.WithError(err).Fatal("Failed writing plan")
 			}
 		}
+	},
+}
 
-		// compile client filters
-		exp, err := expression.Compile(clientFilter)
-		if err != nil {
-			log.WithError(err).Fatal("Failed compiling client filters")
-		}
-
-		// load client object
-		c, err := client.NewClient(*clientType, clientName, exp)
+func init() {
+	rootCmd.AddCommand(retagCmd)
 
-		ct, ok := c.(client.TagInterface)
-		if !ok {
-			log.Fatalf("Retagging is currently only supported for qbittorrent")
-		}
+	retagCmd.Flags().BoolVar(&flagAll, "all", false, "Run against all enabled clients")
+	retagCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	retagCmd.Flags().StringVar(&flagPlanOut, "plan-out", "", "Write planned actions to file instead of executing them")
+}
 
+func runRetag(log *logrus.Entry, clientName string, result *runResult) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// retrieve client filters
+	clientFilter, err := getClientFilter(clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client filter: %w", err)
+	}
+
+	if flagFilterName != "" {
+		clientFilter, err = getFilter(flagFilterName)
 		if err != nil {
-			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
-		}
-
-		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, ct.Type(), tracker.Loaded())
-
-		// connect to client
-		if err := ct.Connect(); err != nil {
-			log.WithError(err).Fatal("Failed connecting")
-		} else {
-			log.Debugf("Connected to client")
-		}
-
-		// get free disk space (can/will be used by filters)
-		if clientFreeSpacePath != nil {
-			space, err := ct.GetCurrentFreeSpace(*clientFreeSpacePath)
-			if err != nil {
-				log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
-			} else {
-				log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
-					humanize.IBytes(uint64(space)), ct.GetFreeSpace())
-			}
-		}
-
-		// retrieve torrents
-		torrents, err := ct.GetTorrents()
+			return fmt.Errorf("retrieve specified filter: %w", err)
+		}
+	}
+
+	// compile client filters
+	exp, err := expression.Compile(clientFilter)
+	if err != nil {
+		return fmt.Errorf("compile client filters: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	ct, ok := c.(client.TagInterface)
+	if !ok {
+		return errors.New("retagging is currently only supported for qbittorrent and transmission")
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, ct.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := ct.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// get free disk space (can/will be used by filters)
+	if clientFreeSpacePath != nil {
+		space, err := ct.GetCurrentFreeSpace(*clientFreeSpacePath)
 		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving torrents")
+			log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
 		} else {
-			log.Infof("Retrieved %d torrents", len(torrents))
-		}
+			log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
+				humanize.IBytes(uint64(space)), ct.GetFreeSpace())
 
-		if flagLogLevel > 1 {
-			if b, err := json.Marshal(torrents); err != nil {
-				log.WithError(err).Error("Failed marshalling torrents")
-			} else {
-				log.Trace(string(b))
-			}
+			freeSpaceGB := ct.GetFreeSpace()
+			result.FreeSpaceGB = &freeSpaceGB
 		}
+	}
 
-		if sliceutils.StringSliceContains(clientFilter.MapHardlinksFor, "retag", true) {
-			// download path mapping
-			clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
-			if err != nil {
-				log.WithError(err).Fatal("Failed loading client download path mappings")
-			} else if clientDownloadPathMapping != nil {
-				log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
-					clientDownloadPathMapping)
-			}
+	// retrieve torrents
+	torrents, err := ct.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
 
-			// create map of paths associated to underlying file ids
-			start := time.Now()
-			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
-			log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+	result.Torrents = len(torrents)
 
-			// add HardlinkedOutsideClient field to torrents
-			for h, t := range torrents {
-				t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
-				torrents[h] = t
-			}
-		} else {
-			log.Warnf("Not mapping hardlinks for client %q", clientName)
-			log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'retag' to the 'MapHardlinksFor' field in your filter configuration")
-		}
+	// set fields tracked across runs
+	if err := setTorrentState(log, clientName, torrents, !result.DryRun,
+		tracksUnregistered(exp, nil)); err != nil {
+		return fmt.Errorf("set torrent state: %w", err)
+	}
 
-		// Verify tags exist on client
-		var tagList []string = []string{}
-		for _, v := range exp.Tags {
-			tagList = append(tagList, v.Name)
-		}
-		if err := ct.CreateTags(tagList); err != nil {
-			log.WithError(err).Fatal("Failed to create tags on client")
+	if flagLogLevel > 1 {
+		if b, err := json.Marshal(torrents); err != nil {
+			log.WithError(err).Error("Failed marshalling torrents")
 		} else {
-			log.Infof("Verified tags exist on client")
-		}
-
-		// relabel torrents that meet the filter criteria
-		if err := retagEligibleTorrents(log, ct, torrents); err != nil {
-			log.WithError(err).Fatal("Failed retagging eligible torrents...")
+			log.Trace(string(b))
 		}
-	},
-}
+	}
 
-func init() {
-	rootCmd.AddCommand(retagCmd)
-
-	retagCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	if sliceutils.StringSliceContains(clientFilter.MapHardlinksFor, "retag", true) {
+		// download path mapping
+		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+		if err != nil {
+			return fmt.Errorf("load client download path mappings: %w", err)
+		} else if clientDownloadPathMapping != nil {
+			log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
+				clientDownloadPathMapping)
+		}
+
+		// create map of paths associated to underlying file ids
+		start := time.Now()
+		hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
+		log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+
+		// add HardlinkedOutsideClient field to torrents
+		for h, t := range torrents {
+			t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
+			torrents[h] = t
+		}
+	} else {
+		log.Warnf("Not mapping hardlinks for client %q", clientName)
+		log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'retag' to the 'MapHardlinksFor' field in your filter configuration")
+	}
+
+	// Verify tags exist on client
+	var tagList []string = []string{}
+	for _, v := range exp.Tags {
+		tagList = append(tagList, v.Name)
+	}
+	if err := ct.CreateTags(tagList); err != nil {
+		return fmt.Errorf("create tags on client: %w", err)
+	} else {
+		log.Infof("Verified tags exist on client")
+	}
+
+	// relabel torrents that meet the filter criteria
+	if err := retagEligibleTorrents(log, ct, torrents, result, exp.OrderBy); err != nil {
+		return fmt.Errorf("retag eligible torrents: %w", err)
+	}
+
+	return nil
 }
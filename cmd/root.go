@@ -6,7 +6,10 @@ import (
 	"path/filepath"
 
 	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
 	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/notification"
+	"github.com/autobrr/tqm/recycle"
 	"github.com/autobrr/tqm/runtime"
 	"github.com/autobrr/tqm/stringutils"
 	"github.com/autobrr/tqm/tracker"
@@ -26,6 +29,8 @@ var (
 	flagFilterName                       string
 	flagDryRun                           bool
 	flagExperimentalRelabelForCrossSeeds bool
+	flagPlanOut                          string
+	flagAll                              bool
 
 	// Global vars
 	log         *logrus.Entry
@@ -176,3 +181,140 @@ func getFilter(filterName string) (*config.FilterConfiguration, error) {
 
 	return &clientFilter, nil
 }
+
+func getClientFreeSpaceTarget(clientName string, clientConfig map[string]interface{}) (*freeSpaceTarget, error) {
+	v, ok := clientConfig["free_space_target"]
+	if !ok {
+		return nil, nil
+	}
+
+	var gb float64
+	switch vv := v.(type) {
+	case int:
+		gb = float64(vv)
+	case int64:
+		gb = float64(vv)
+	case float64:
+		gb = vv
+	default:
+		return nil, fmt.Errorf("failed type-asserting free_space_target of client: %#v", v)
+	}
+
+	if gb <= 0 {
+		return nil, fmt.Errorf("free_space_target must be greater than 0: %v", v)
+	}
+
+	// remove the largest torrents first, unless configured otherwise
+	sortConfig := config.SortConfiguration{Expr: "DownloadedBytes", Order: "desc"}
+	if _, ok := clientConfig["free_space_sort"]; ok {
+		sortConfig = config.SortConfiguration{}
+		if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%sfree_space_sort", config.Delimiter, clientName,
+			config.Delimiter), &sortConfig); err != nil {
+			return nil, fmt.Errorf("unmarshal free_space_sort of client: %w", err)
+		}
+	}
+
+	s, err := expression.CompileSort(sortConfig)
+	if err != nil {
+		return nil, fmt.Errorf("compile free_space_sort of client: %w", err)
+	}
+
+	return &freeSpaceTarget{GB: gb, Sort: s}, nil
+}
+
+func getClientRemovalLimits(clientName string, clientConfig map[string]interface{}) (*config.RemovalLimitsConfiguration, error) {
+	if _, ok := clientConfig["removal_limits"]; !ok {
+		return nil, nil
+	}
+
+	limits := new(config.RemovalLimitsConfiguration)
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%sremoval_limits", config.Delimiter, clientName,
+		config.Delimiter), limits); err != nil {
+		return nil, fmt.Errorf("unmarshal removal_limits of client: %w", err)
+	}
+
+	return limits, nil
+}
+
+// getClientOrphanSettings returns the orphan settings of a client, empty when not configured.
+func getClientOrphanSettings(clientName string, clientConfig map[string]interface{}) (*config.OrphanConfiguration, error) {
+	settings := new(config.OrphanConfiguration)
+	if _, ok := clientConfig["orphan"]; !ok {
+		return settings, nil
+	}
+
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%sorphan", config.Delimiter, clientName,
+		config.Delimiter), settings); err != nil {
+		return nil, fmt.Errorf("unmarshal orphan of client: %w", err)
+	}
+
+	return settings, nil
+}
+
+// getClientRecycleBin returns the recycle bin of a client, or nil when not configured.
+func getClientRecycleBin(clientConfig map[string]interface{}) (*recycle.Bin, error) {
+	if _, ok := clientConfig["recycle_path"]; !ok {
+		return nil, nil
+	}
+
+	recyclePath, err := getClientConfigString("recycle_path", clientConfig)
+	if err != nil {
+		return nil, err
+	} else if *recyclePath == "" {
+		return nil, errors.New("recycle_path must not be empty")
+	}
+
+	// files are kept relative to the download path
+	var root string
+	if downloadPath, err := getClientConfigString("download_path", clientConfig); err == nil {
+		root = *downloadPath
+	}
+
+	pathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("load client download path mappings: %w", err)
+	}
+
+	return recycle.New(*recyclePath, root, pathMapping), nil
+}
+
+// getClientSharedClients returns the names of the clients a client shares data with.
+func getClientSharedClients(clientName string, clientConfig map[string]interface{}) ([]string, error) {
+	v, ok := clientConfig["share_data_with"]
+	if !ok {
+		return nil, nil
+	}
+
+	tmp, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed type-asserting share_data_with of client: %#v", v)
+	}
+
+	sharedClients := make([]string, 0, len(tmp))
+	for _, v := range tmp {
+		name, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("failed type-asserting share_data_with of client: %#v", v)
+		} else if name == clientName {
+			continue
+		}
+
+		if _, ok := config.Config.Clients[name]; !ok {
+			return nil, fmt.Errorf("no client configuration found for shared client: %q", name)
+		}
+
+		sharedClients = append(sharedClients, name)
+	}
+
+	return sharedClients, nil
+}
+
+func getClientNotifier(clientName string) (*notification.Notifier, error) {
+	var cfg notification.Config
+	if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%snotifications", config.Delimiter, clientName,
+		config.Delimiter), &cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal notifications of client: %w", err)
+	}
+
+	return notification.New(clientName, cfg)
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/journal"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/metrics"
+	"github.com/autobrr/tqm/notification"
+	"github.com/autobrr/tqm/plan"
+
+	"github.com/sirupsen/logrus"
+)
+
+type commandFunc func(*logrus.Entry, string, *runResult) error
+
+// runResult holds the outcome of a command run against a client
+type runResult struct {
+	RunID    string    `json:"run_id"`
+	Client   string    `json:"client"`
+	Command  string    `json:"command"`
+	DryRun   bool      `json:"dry_run"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+
+	// torrents
+	Torrents       int      `json:"torrents"`
+	FreeSpaceGB    *float64 `json:"free_space_gb,omitempty"`
+	Ignored        int      `json:"ignored"`
+	NonUnique      int      `json:"non_unique"`
+	Removed        int      `json:"removed"`
+	Kept           int      `json:"kept,omitempty"`
+	Relabeled      int      `json:"relabeled"`
+	Retagged       int      `json:"retagged"`
+	Failures       int      `json:"failures"`
+	ReclaimedBytes uint64   `json:"reclaimed_bytes"`
+
+	// orphans
+	OrphanFiles   int `json:"orphan_files"`
+	OrphanFolders int `json:"orphan_folders"`
+	// orphan folders kept as they were not empty
+	OrphanFoldersNonEmpty int `json:"orphan_folders_non_empty,omitempty"`
+	// orphans kept by the orphan settings of the client, by reason
+	OrphansSkipped map[string]int `json:"orphans_skipped,omitempty"`
+	// orphans removed by download path
+	OrphanRoots []*orphanRoot `json:"orphan_roots,omitempty"`
+
+	// details
+	RemovedTorrents []notification.Torrent `json:"removed_torrents,omitempty"`
+	FailedItems     []notification.Failure `json:"failed_items,omitempty"`
+
+	notifier *notification.Notifier
+	// actions taken (or skipped by dry-run), written by --plan-out
+	planned []plan.Action
+	// changes recorded in the journal
+	journaled int
+}
+
+var (
+	// commands that can be run by the daemon, in the order they are run when due at the same time
+	runnableCommands = []string{"clean", "relabel", "retag", "orphan"}
+
+	commandFuncs = map[string]commandFunc{
+		"clean":   runClean,
+		"relabel": runRelabel,
+		"retag":   runRetag,
+		"orphan":  runOrphan,
+	}
+
+	// ensures only a single command runs at any given time
+	runLock sync.Mutex
+
+	// result of the last run of each command, by client
+	lastResults     = make(map[string]map[string]*runResult)
+	lastResultsLock sync.Mutex
+)
+
+// runCommand runs command against a client, waiting for any other running command to finish first.
+func runCommand(command string, clientName string, dryRun bool) (*runResult, error) {
+	fn, ok := commandFuncs[command]
+	if !ok {
+		return nil, fmt.Errorf("unsupported command: %q", command)
+	}
+
+	return runCommandFunc(command, clientName, dryRun, fn)
+}
+
+// runCommandFunc runs fn as command against a client, see runCommand.
+func runCommandFunc(command string, clientName string, dryRun bool, fn commandFunc) (*runResult, error) {
+	runLock.Lock()
+	defer runLock.Unlock()
+
+	started := time.Now()
+	result := &runResult{
+		RunID:   journal.NewRunID(started),
+		Client:  clientName,
+		Command: command,
+		// dry-run can be enabled per run, but never disabled when set globally
+		DryRun:  flagDryRun || dryRun,
+		Started: started,
+	}
+
+	log := logger.GetLogger(command)
+
+	notifier, err := getClientNotifier(clientName)
+	if err != nil {
+		err = fmt.Errorf("load notifications: %w", err)
+	} else {
+		result.notifier = notifier
+		err = fn(log, clientName, result)
+	}
+
+	if result.journaled > 0 {
+		log.Infof("Journaled %d changes, undo with: tqm undo %s", result.journaled, result.RunID)
+	}
+
+	result.Finished = time.Now()
+	result.Duration = result.Finished.Sub(result.Started).String()
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	recordRunMetrics(result)
+	notifyRun(log, result)
+
+	// store result
+	lastResultsLock.Lock()
+	if _, ok := lastResults[clientName]; !ok {
+		lastResults[clientName] = make(map[string]*runResult)
+	}
+	lastResults[clientName][command] = result
+	lastResultsLock.Unlock()
+
+	return result, err
+}
+
+func getLastResult(clientName string, command string) *runResult {
+	lastResultsLock.Lock()
+	defer lastResultsLock.Unlock()
+
+	if results, ok := lastResults[clientName]; ok {
+		return results[command]
+	}
+
+	return nil
+}
+
+func recordRunMetrics(result *runResult) {
+	status := "success"
+	if result.Error != "" {
+		status = "failure"
+	}
+
+	metrics.Add(metrics.Runs, 1, "client", result.Client, "command", result.Command, "status", status,
+		"dry_run", strconv.FormatBool(result.DryRun))
+	metrics.Set(metrics.RunDuration, result.Finished.Sub(result.Started).Seconds(), "client", result.Client,
+		"command", result.Command)
+	metrics.Set(metrics.LastRunTimestamp, float64(result.Finished.Unix()), "client", result.Client,
+		"command", result.Command)
+
+	if result.Torrents > 0 {
+		metrics.Set(metrics.Torrents, float64(result.Torrents), "client", result.Client)
+	}
+
+	if result.FreeSpaceGB != nil {
+		metrics.Set(metrics.FreeSpaceGB, *result.FreeSpaceGB, "client", result.Client)
+	}
+
+	// actions are not recorded for dry-runs
+	if result.DryRun {
+		return
+	}
+
+	labels := []string{"client", result.Client, "command", result.Command}
+	metrics.Add(metrics.IgnoredTorrents, float64(result.Ignored), labels...)
+	metrics.Add(metrics.Failures, float64(result.Failures), labels...)
+	metrics.Add(metrics.ReclaimedBytes, float64(result.ReclaimedBytes), labels...)
+
+	switch result.Command {
+	case "clean":
+		metrics.Add(metrics.RemovedTorrents, float64(result.Removed), "client", result.Client)
+	case "relabel":
+		metrics.Add(metrics.RelabeledTorrents, float64(result.Relabeled), "client", result.Client)
+	case "retag":
+		metrics.Add(metrics.RetaggedTorrents, float64(result.Retagged), "client", result.Client)
+	case "orphan":
+		metrics.Add(metrics.RemovedOrphans, float64(result.OrphanFiles), "client", result.Client, "type", "file")
+		metrics.Add(metrics.RemovedOrphans, float64(result.OrphanFolders), "client", result.Client, "type", "folder")
+	}
+}
+
+// notifyRun sends the summary of a run to the notification targets of its client.
+func notifyRun(log *logrus.Entry, result *runResult) {
+	if !result.notifier.Enabled() {
+		return
+	}
+
+	if err := result.notifier.SendSummary(&notification.Summary{
+		Client:          result.Client,
+		Command:         result.Command,
+		DryRun:          result.DryRun,
+		Duration:        result.Duration,
+		Error:           result.Error,
+		Torrents:        result.Torrents,
+		Ignored:         result.Ignored,
+		Removed:         result.Removed,
+		Relabeled:       result.Relabeled,
+		Retagged:        result.Retagged,
+		OrphanFiles:     result.OrphanFiles,
+		OrphanFolders:   result.OrphanFolders,
+		ReclaimedBytes:  result.ReclaimedBytes,
+		RemovedTorrents: result.RemovedTorrents,
+		Failures:        result.FailedItems,
+	}); err != nil {
+		log.WithError(err).Error("Failed sending notification")
+	}
+}
+
+// notifyEvent sends an individual action of a run, when events are enabled for the command.
+func notifyEvent(log *logrus.Entry, result *runResult, e notification.Event) {
+	if !result.notifier.EventsEnabled(result.Command) {
+		return
+	}
+
+	e.Client = result.Client
+	e.Command = result.Command
+	e.DryRun = result.DryRun
+
+	if err := result.notifier.SendEvent(&e); err != nil {
+		log.WithError(err).Error("Failed sending notification")
+	}
+}
+
+// addFailure records a failed torrent or orphan of a run.
+func (r *runResult) addFailure(name string, err error) {
+	r.FailedItems = append(r.FailedItems, notification.Failure{
+		Name:  name,
+		Error: err.Error(),
+	})
+}
+
+// addPlanned records an action for a torrent, filling in its details and current state.
+func (r *runResult) addPlanned(t *config.Torrent, a plan.Action) {
+	a.Hash = t.Hash
+	a.Name = t.Name
+	a.Bytes = t.DownloadedBytes
+	a.State = plan.NewState(t)
+
+	r.planned = append(r.planned, a)
+}
+
+// addJournal records a change applied to a torrent in the journal, so the run can be undone,
+// the previous label and tags are taken from the torrent.
+func (r *runResult) addJournal(log *logrus.Entry, t *config.Torrent, e journal.Entry) {
+	e.RunID = r.RunID
+	e.Time = time.Now()
+	e.Client = r.Client
+	e.Command = r.Command
+	e.Hash = t.Hash
+	e.Name = t.Name
+	e.OldLabel = t.Label
+	e.OldTags = append(make([]string, 0, len(t.Tags)), t.Tags...)
+	if e.NewTags == nil {
+		e.NewTags = make([]string, 0)
+	}
+
+	if err := journal.Append(filepath.Join(flagConfigFolder, journalFile), e); err != nil {
+		log.WithError(err).Errorf("Failed journaling %s of torrent: %q", e.Action, t.Name)
+		return
+	}
+
+	r.journaled++
+}
+
+// writePlan writes the actions of a run to path, to be applied later.
+func writePlan(log *logrus.Entry, result *runResult, path string) error {
+	if result == nil {
+		return nil
+	}
+
+	if err := plan.New(result.Client, result.Command, result.planned).Save(path); err != nil {
+		return fmt.Errorf("save plan: %q: %w", path, err)
+	}
+
+	log.Infof("Wrote plan with %d actions to: %q", len(result.planned), path)
+	return nil
+}
+
+// writeMetricsTextfile writes the metrics of a run to the configured node_exporter textfile collector directory.
+func writeMetricsTextfile(log *logrus.Entry, result *runResult) {
+	if config.Config.Metrics.TextfileDirectory == "" || result == nil {
+		return
+	}
+
+	path := filepath.Join(config.Config.Metrics.TextfileDirectory,
+		fmt.Sprintf("tqm_%s_%s.prom", result.Command, result.Client))
+	if err := metrics.WriteTextfile(path, map[string]string{
+		"client":  result.Client,
+		"command": result.Command,
+	}); err != nil {
+		log.WithError(err).Errorf("Failed writing metrics to: %q", path)
+		return
+	}
+
+	log.Debugf("Wrote metrics to: %q", path)
+}
@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/expression"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+type torrentOutcome struct {
+	config.Torrent
+
+	Unregistered bool              `json:"Unregistered"`
+	Ignore       bool              `json:"Ignore"`
+	IgnoreMatch  *expression.Match `json:"IgnoreMatch,omitempty"`
+	Remove       bool              `json:"Remove"`
+	RemoveMatch  *expression.Match `json:"RemoveMatch,omitempty"`
+	Relabel      string            `json:"Relabel,omitempty"`
+	RelabelMatch *expression.Match `json:"RelabelMatch,omitempty"`
+	Retag        *client.RetagInfo `json:"Retag,omitempty"`
+	Error        string            `json:"Error,omitempty"`
+}
+
+type server struct {
+	log  *logrus.Entry
+	http *http.Server
+
+	// runs that have been triggered but not yet finished, by client/command
+	pending     map[string]bool
+	pendingLock sync.Mutex
+}
+
+func newServer(cfg config.ServerConfiguration) *server {
+	s := &server{
+		log:     logger.GetLogger("server"),
+		pending: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/clients/{client}/{command}", s.handleRun)
+	mux.HandleFunc("GET /api/clients/{client}/{command}/last", s.handleLastResult)
+	mux.HandleFunc("GET /api/clients/{client}/torrents", s.handleTorrents)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	s.http = &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           s.authenticate(cfg.ApiKey, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+/* Server */
+
+func (s *server) Start() {
+	go func() {
+		s.log.Infof("Listening on %s", s.http.Addr)
+		if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.WithError(err).Fatal("Failed listening")
+		}
+	}()
+}
+
+func (s *server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.http.Shutdown(ctx); err != nil {
+		s.log.WithError(err).Error("Failed shutting down")
+	}
+}
+
+func (s *server) authenticate(apiKey string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey != "" {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				key = r.URL.Query().Get("apikey")
+			}
+
+			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+				s.writeError(w, http.StatusUnauthorized, errors.New("invalid api key"))
+				return
+			}
+		}
+
+		s.log.Debugf("%s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+/* Handlers */
+
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	clientName := r.PathValue("client")
+	command := r.PathValue("command")
+
+	if _, ok := commandFuncs[command]; !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("unsupported command: %q", command))
+		return
+	}
+
+	if _, ok := config.Config.Clients[clientName]; !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("no client configuration found for: %q", clientName))
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("parse dry_run: %w", err))
+			return
+		}
+		dryRun = b
+	}
+
+	// prevent queueing the same run multiple times
+	key := clientName + "/" + command
+
+	s.pendingLock.Lock()
+	if s.pending[key] {
+		s.pendingLock.Unlock()
+		s.writeError(w, http.StatusConflict, fmt.Errorf("%s of %q is already queued or running", command, clientName))
+		return
+	}
+	s.pending[key] = true
+	s.pendingLock.Unlock()
+
+	go func() {
+		defer func() {
+			s.pendingLock.Lock()
+			delete(s.pending, key)
+			s.pendingLock.Unlock()
+		}()
+
+		s.log.Infof("Running %s for %s (dry-run: %v)", command, clientName, dryRun)
+		if _, err := runCommand(command, clientName, dryRun); err != nil {
+			s.log.WithError(err).Errorf("Failed running %s for %s", command, clientName)
+		}
+	}()
+
+	s.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"client":  clientName,
+		"command": command,
+		"dry_run": dryRun || flagDryRun,
+		"status":  "queued",
+	})
+}
+
+func (s *server) handleLastResult(w http.ResponseWriter, r *http.Request) {
+	clientName := r.PathValue("client")
+	command := r.PathValue("command")
+
+	result := getLastResult(clientName, command)
+	if result == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("no %s run found for: %q", command, clientName))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *server) handleTorrents(w http.ResponseWriter, r *http.Request) {
+	clientName := r.PathValue("client")
+
+	outcomes, err := evaluateClientTorrents(clientName, r.URL.Query().Get("filter"))
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, outcomes)
+}
+
+/* Helpers */
+
+func (s *server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.log.WithError(err).Error("Failed encoding response")
+	}
+}
+
+func (s *server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// evaluateClientTorrents retrieves the torrents of a client along with the outcome of its filters.
+func evaluateClientTorrents(clientName string, filterName string) ([]torrentOutcome, error) {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return nil, fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// retrieve client filters
+	var clientFilter *config.FilterConfiguration
+	if filterName != "" {
+		clientFilter, err = getFilter(filterName)
+	} else {
+		clientFilter, err = getClientFilter(clientConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("retrieve filter: %w", err)
+	}
+
+	// compile client filters
+	exp, err := expression.Compile(clientFilter)
+	if err != nil {
+		return nil, fmt.Errorf("compile filters: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		return nil, fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	if err := c.Connect(); err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	// get free disk space (can/will be used by filters)
+	if clientFreeSpacePath != nil {
+		if _, err := c.GetCurrentFreeSpace(*clientFreeSpacePath); err != nil {
+			return nil, fmt.Errorf("retrieve free-space: %q: %w", *clientFreeSpacePath, err)
+		}
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve torrents: %w", err)
+	}
+
+	if err := setTorrentState(logger.GetLogger("server"), clientName, torrents, false, false); err != nil {
+		return nil, fmt.Errorf("set torrent state: %w", err)
+	}
+
+	// evaluate filters
+	outcomes := make([]torrentOutcome, 0, len(torrents))
+	for _, t := range torrents {
+		t := t
+		// tracker apis are not queried for listing torrents, only the tracker status is checked
+		o := torrentOutcome{
+			Torrent:      t,
+			Unregistered: t.HasUnregisteredStatus(),
+		}
+
+		ignore, ignoreMatch, err := c.ShouldIgnore(&t)
+		if err != nil {
+			o.Error = err.Error()
+			outcomes = append(outcomes, o)
+			continue
+		}
+		o.Ignore = ignore && !(config.Config.BypassIgnoreIfUnregistered && o.Unregistered)
+		if o.Ignore {
+			o.IgnoreMatch = ignoreMatch
+		}
+
+		if !o.Ignore {
+			if o.Remove, o.RemoveMatch, err = c.ShouldRemove(&t); err != nil {
+				o.Error = err.Error()
+			}
+		}
+
+		if label, relabel, match, err := c.ShouldRelabel(&t); err != nil {
+			o.Error = err.Error()
+		} else if relabel && label != t.Label {
+			o.Relabel = label
+			o.RelabelMatch = match
+		}
+
+		if ct, ok := c.(client.TagInterface); ok {
+			if retagInfo, retag, err := ct.ShouldRetag(&t); err != nil {
+				o.Error = err.Error()
+			} else if retag {
+				o.Retag = &retagInfo
+			}
+		}
+
+		outcomes = append(outcomes, o)
+	}
+
+	return outcomes, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestEvaluateClientTorrentsDuringRun(t *testing.T) {
+	setupSnapshotConfig(t)
+
+	// listing torrents while a dry-run is running must not race with it (go test -race)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := runCommand("clean", "snapshot", true); err != nil {
+			t.Errorf("run clean: %v", err)
+		}
+	}()
+
+	outcomes, err := evaluateClientTorrents("snapshot", "")
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("evaluate torrents: %v", err)
+	}
+
+	unregistered := make(map[string]bool)
+	for _, o := range outcomes {
+		unregistered[o.Hash] = o.Unregistered
+	}
+	if !unregistered["AAAA"] || unregistered["CCCC"] {
+		t.Errorf("expected only AAAA to be unregistered by its tracker status, got %v", unregistered)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/pathindex"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadSharedTorrents retrieves the torrents of the clients a client shares data with, so their files are never
+// considered unique to the client. Files are mapped to paths as seen by the client and hashes are prefixed with
+// the name of their client, so torrents seeded by both clients are kept apart.
+func loadSharedTorrents(log *logrus.Entry, clientName string, clientConfig map[string]interface{}) (
+	map[string]config.Torrent, error) {
+	sharedClients, err := getClientSharedClients(clientName, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve shared clients: %w", err)
+	} else if len(sharedClients) == 0 {
+		return nil, nil
+	}
+
+	clientPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("load client download path mappings: %w", err)
+	}
+
+	shared := make(map[string]config.Torrent)
+	for _, sharedName := range sharedClients {
+		torrents, err := getSharedClientTorrents(sharedName)
+		if err != nil {
+			// data must not be removed without knowing what the shared client seeds
+			return nil, fmt.Errorf("shared client %q: %w", sharedName, err)
+		}
+
+		sharedPathMapping, err := getClientDownloadPathMapping(config.Config.Clients[sharedName])
+		if err != nil {
+			return nil, fmt.Errorf("load download path mappings of shared client %q: %w", sharedName, err)
+		}
+
+		for _, t := range torrents {
+			files := make([]string, 0, len(t.Files))
+			for _, f := range t.Files {
+				files = append(files, pathindex.UnmapPath(pathindex.MapPath(f, sharedPathMapping), clientPathMapping))
+			}
+
+			t.Hash = sharedName + "/" + t.Hash
+			t.Files = files
+			shared[t.Hash] = t
+		}
+
+		log.Infof("Retrieved %d torrents of shared client %q", len(torrents), sharedName)
+	}
+
+	return shared, nil
+}
+
+func getSharedClientTorrents(clientName string) (map[string]config.Torrent, error) {
+	// retrieve client type
+	clientType, err := getClientConfigString("type", config.Config.Clients[clientName])
+	if err != nil {
+		return nil, fmt.Errorf("determine client type: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("initialize client: %w", err)
+	}
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("retrieve torrents: %w", err)
+	}
+
+	return torrents, nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/logger"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [CLIENT]",
+	Short: "Write the torrents of a client to stdout as json",
+	Long:  `This command can be used to capture the torrents of a client, to be used by a client of type snapshot.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(false)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("snapshot")
+
+		// snapshot client
+		if err := runSnapshot(log, args[0]); err != nil {
+			log.WithError(err).Fatal("Failed creating snapshot")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func runSnapshot(log *logrus.Entry, clientName string) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, nil)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", clientName, err)
+	}
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	snapshot := &client.SnapshotFile{
+		Client:  clientName,
+		Type:    c.Type(),
+		Created: time.Now(),
+	}
+
+	// get free disk space
+	if clientFreeSpacePath != nil {
+		space, err := c.GetCurrentFreeSpace(*clientFreeSpacePath)
+		if err != nil {
+			log.WithError(err).Warnf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
+		} else {
+			snapshot.FreeSpace = &space
+		}
+	}
+
+	// load client label path map
+	if err := c.LoadLabelPathMap(); err != nil {
+		return fmt.Errorf("load label path map: %w", err)
+	}
+	snapshot.LabelPathMap = c.LabelPathMap()
+	if sc, ok := c.(client.SavePathInterface); ok {
+		snapshot.DefaultSavePath = sc.DefaultSavePath()
+	}
+
+	// retrieve torrents
+	snapshot.Torrents, err = c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	}
+
+	log.Infof("Retrieved %d torrents", len(snapshot.Torrents))
+
+	if err := setTorrentState(log, clientName, snapshot.Torrents, false, false); err != nil {
+		return fmt.Errorf("set torrent state: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snapshot); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return nil
+}
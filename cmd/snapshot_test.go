@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/plan"
+
+	"github.com/knadh/koanf"
+)
+
+const snapshotTestConfig = `
+clients:
+  snapshot:
+    enabled: true
+    type: snapshot
+    path: %s
+    filter: default
+    free_space_path: /downloads
+filters:
+  default:
+    ignore:
+      - '"permaseed" in Tags'
+      - Downloaded == false && !IsUnregistered()
+    remove:
+      - name: unregistered
+        expr: IsUnregistered()
+      - name: seeded
+        expr: Ratio >= 2.0 && SeedingDays >= 14
+    label:
+      - name: tv
+        update:
+          - Label == "incoming" && Downloaded
+    tag:
+      - name: low-seeds
+        mode: full
+        update:
+          - Seeds <= 3
+`
+
+// setupSnapshotConfig loads a config with a snapshot client serving testdata/snapshot.json,
+// along with the extra clients given.
+func setupSnapshotConfig(t *testing.T, extraClients ...string) {
+	t.Helper()
+
+	snapshotPath, err := filepath.Abs(filepath.Join("testdata", "snapshot.json"))
+	if err != nil {
+		t.Fatalf("snapshot path: %v", err)
+	}
+
+	cfg := fmt.Sprintf(snapshotTestConfig, snapshotPath)
+	cfg = strings.Replace(cfg, "filters:", strings.Join(extraClients, "")+"filters:", 1)
+	loadTestConfig(t, t.TempDir(), cfg)
+}
+
+// loadTestConfig writes a config into dir, which becomes the config folder, and loads it.
+func loadTestConfig(t *testing.T, dir string, cfg string) {
+	t.Helper()
+
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(cfg), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	prevConfigFolder := flagConfigFolder
+	flagConfigFolder = dir
+	t.Cleanup(func() {
+		flagConfigFolder = prevConfigFolder
+	})
+
+	config.K = koanf.New(config.Delimiter)
+	if err := config.Init(configPath); err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+}
+
+// plannedActions runs a command against the snapshot client while planning and returns its actions by hash.
+func plannedActions(t *testing.T, command string) (*runResult, map[string]plan.Action) {
+	t.Helper()
+
+	result, err := runCommand(command, "snapshot", true)
+	if err != nil {
+		t.Fatalf("run %s: %v", command, err)
+	}
+
+	actions := make(map[string]plan.Action, len(result.planned))
+	for _, a := range result.planned {
+		actions[a.Hash] = a
+	}
+
+	return result, actions
+}
+
+func actionHashes(actions map[string]plan.Action) []string {
+	hashes := make([]string, 0, len(actions))
+	for h := range actions {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	return hashes
+}
+
+func TestSnapshotClean(t *testing.T) {
+	setupSnapshotConfig(t)
+
+	result, actions := plannedActions(t, "clean")
+
+	if want := []string{"AAAA", "CCCC"}; !slices.Equal(actionHashes(actions), want) {
+		t.Fatalf("expected removals of %v, got %v", want, actionHashes(actions))
+	}
+	if a := actions["AAAA"]; a.Action != plan.ActionRemove || a.Rule != "remove[0] unregistered: IsUnregistered()" {
+		t.Errorf("expected AAAA to be removed as unregistered, got %+v", a)
+	}
+	if a := actions["CCCC"]; a.Action != plan.ActionRemove || a.Rule != "remove[1] seeded: Ratio >= 2.0 && SeedingDays >= 14" {
+		t.Errorf("expected CCCC to be removed as seeded, got %+v", a)
+	}
+	if a := actions["AAAA"]; a.Reason != `matched remove filter (unregistered: "Unregistered torrent")` {
+		t.Errorf("expected AAAA to be removed as unregistered, got reason %q", a.Reason)
+	}
+	if a := actions["CCCC"]; a.Reason != "matched remove filter" {
+		t.Errorf("expected CCCC not to be removed as unregistered, got reason %q", a.Reason)
+	}
+
+	if result.Torrents != 5 || result.Ignored != 2 || result.Removed != 2 {
+		t.Errorf("expected 5 torrents, 2 ignored and 2 removed, got %d, %d and %d",
+			result.Torrents, result.Ignored, result.Removed)
+	}
+	if result.FreeSpaceGB == nil || *result.FreeSpaceGB != 100 {
+		t.Errorf("expected free space of the snapshot, got %v", result.FreeSpaceGB)
+	}
+	if !result.DryRun {
+		t.Error("expected planning run to be a dry-run")
+	}
+}
+
+func TestSnapshotRelabel(t *testing.T) {
+	setupSnapshotConfig(t)
+
+	_, actions := plannedActions(t, "relabel")
+
+	if want := []string{"DDDD"}; !slices.Equal(actionHashes(actions), want) {
+		t.Fatalf("expected relabel of %v, got %v", want, actionHashes(actions))
+	}
+	if a := actions["DDDD"]; a.Action != plan.ActionRelabel || a.Label != "tv" || a.State.Label != "incoming" {
+		t.Errorf("expected DDDD to be relabeled from incoming to tv, got %+v", a)
+	}
+}
+
+func TestSnapshotRetag(t *testing.T) {
+	setupSnapshotConfig(t)
+
+	_, actions := plannedActions(t, "retag")
+
+	// ignore filters do not apply to tags, so the unregistered AAAA and the downloading EEEE are retagged too
+	if want := []string{"AAAA", "DDDD", "EEEE"}; !slices.Equal(actionHashes(actions), want) {
+		t.Fatalf("expected retags of %v, got %v", want, actionHashes(actions))
+	}
+	if a := actions["DDDD"]; !slices.Equal(a.AddTags, []string{"low-seeds"}) || len(a.RemoveTags) != 0 {
+		t.Errorf("expected low-seeds to be added to DDDD, got %+v", a)
+	}
+	if a := actions["EEEE"]; !slices.Equal(a.RemoveTags, []string{"low-seeds"}) || len(a.AddTags) != 0 {
+		t.Errorf("expected low-seeds to be removed from EEEE, got %+v", a)
+	}
+}
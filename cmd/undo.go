@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/client"
+	"github.com/autobrr/tqm/config"
+	"github.com/autobrr/tqm/journal"
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/tracker"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [RUN_ID]",
+	Short: "Restore the labels and tags changed by a relabel or retag run",
+	Long:  `This command can be used to restore the previous labels and tags of torrents changed by a run, as recorded in the journal.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("undo")
+
+		// load journal entries of run
+		path := filepath.Join(flagConfigFolder, journalFile)
+		entries, err := journal.Load(path, args[0])
+		if err != nil {
+			log.WithError(err).Fatalf("Failed loading journal: %q", path)
+		} else if len(entries) == 0 {
+			log.Fatalf("No journal entries found for run: %q", args[0])
+		}
+
+		log.Infof("Loaded %d journal entries of %s run %q for %q", len(entries), entries[0].Command, args[0],
+			entries[0].Client)
+
+		// undo run
+		result, err := runCommandFunc("undo", entries[0].Client, false,
+			func(log *logrus.Entry, clientName string, result *runResult) error {
+				return runUndo(log, entries, result)
+			})
+		writeMetricsTextfile(log, result)
+		if err != nil {
+			log.WithError(err).Fatal("Failed undoing run")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(log *logrus.Entry, entries []journal.Entry, result *runResult) error {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[result.Client]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", result.Client)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, result.Client, nil)
+	if err != nil {
+		return fmt.Errorf("initialize client: %q: %w", result.Client, err)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", result.Client, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents()
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	result.Torrents = len(torrents)
+
+	// undo changes, latest first
+	restored := 0
+	failures := 0
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+
+		log.Info("-----")
+		log.Infof("Undoing %s: %q | Rule: %s", e.Action, e.Name, e.Rule)
+
+		t, ok := torrents[e.Hash]
+		if !ok {
+			log.Warnf("Skipping torrent no longer in client: %q", e.Name)
+			result.addFailure(e.Name, errors.New("torrent no longer in client"))
+			failures++
+			continue
+		}
+
+		if err := undoEntry(log, c, &t, e, result); err != nil {
+			log.WithError(err).Errorf("Failed undoing %s: %+v", e.Action, t)
+			result.addFailure(e.Name, err)
+			failures++
+			continue
+		}
+
+		// earlier changes of the same torrent are undone from the restored state
+		torrents[e.Hash] = t
+		restored++
+	}
+
+	// show result
+	log.Info("-----")
+	log.Infof("Restored %d of %d changes, %d failures", restored, len(entries), failures)
+
+	result.Failures = failures
+	if failures > 0 {
+		return fmt.Errorf("%d of %d changes not restored", failures, len(entries))
+	}
+
+	return nil
+}
+
+// undoEntry restores the label or tags a torrent had before the change of a journal entry.
+func undoEntry(log *logrus.Entry, c client.Interface, t *config.Torrent, e journal.Entry, result *runResult) error {
+	switch e.Action {
+	case journal.ActionRelabel:
+		if e.Hardlink {
+			return errors.New("relabels with hardlinks cannot be undone")
+		} else if t.Label != e.NewLabel {
+			return fmt.Errorf("label changed since run: %q", t.Label)
+		}
+
+		log.Infof("Restoring label: %q -> %q", t.Label, e.OldLabel)
+		if result.DryRun {
+			log.Warn("Dry-run enabled, skipping undo...")
+		} else {
+			if err := c.SetTorrentLabel(t.Hash, e.OldLabel, false); err != nil {
+				return fmt.Errorf("set torrent label: %w", err)
+			}
+
+			result.addJournal(log, t, journal.Entry{
+				Action:   journal.ActionRelabel,
+				Rule:     "undo " + e.RunID,
+				NewLabel: e.OldLabel,
+				NewTags:  t.Tags,
+			})
+
+			log.Info("Restored")
+			time.Sleep(5 * time.Second)
+		}
+
+		t.Label = e.OldLabel
+		result.Relabeled++
+	case journal.ActionRetag:
+		ct, ok := c.(client.TagInterface)
+		if !ok {
+			return errors.New("retagging is currently only supported for qbittorrent and transmission")
+		}
+
+		// only the tags changed by the run are restored
+		add := tagsDifference(e.OldTags, e.NewTags)
+		remove := tagsDifference(e.NewTags, e.OldTags)
+		newTags := retaggedTags(t.Tags, add, remove)
+		if slices.Equal(newTags, t.Tags) {
+			log.Info("Tags already restored")
+			break
+		}
+
+		log.Infof("Restoring tags: %s -> %s", strings.Join(t.Tags, ", "), strings.Join(newTags, ", "))
+		if result.DryRun {
+			log.Warn("Dry-run enabled, skipping undo...")
+		} else {
+			if err := ct.AddTags(t.Hash, add); err != nil {
+				return fmt.Errorf("add tags: %w", err)
+			}
+
+			if err := ct.RemoveTags(t.Hash, remove); err != nil {
+				return fmt.Errorf("remove tags: %w", err)
+			}
+
+			result.addJournal(log, t, journal.Entry{
+				Action:   journal.ActionRetag,
+				Rule:     "undo " + e.RunID,
+				NewLabel: t.Label,
+				NewTags:  newTags,
+			})
+
+			log.Info("Restored")
+		}
+
+		t.Tags = newTags
+		result.Retagged++
+	default:
+		return fmt.Errorf("unsupported journal action: %q", e.Action)
+	}
+
+	return nil
+}
+
+// tagsDifference returns the tags of a that are not in b.
+func tagsDifference(a []string, b []string) []string {
+	diff := make([]string, 0)
+	for _, tag := range a {
+		if !slices.Contains(b, tag) {
+			diff = append(diff, tag)
+		}
+	}
+
+	return diff
+}
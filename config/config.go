@@ -17,6 +17,9 @@ type Configuration struct {
 	Filters                    map[string]FilterConfiguration
 	Trackers                   tracker.Config
 	BypassIgnoreIfUnregistered bool
+	Schedule                   map[string]ScheduleConfiguration
+	Server                     ServerConfiguration
+	Metrics                    MetricsConfiguration
 }
 
 /* Vars */
@@ -2,8 +2,8 @@ package config
 
 type FilterConfiguration struct {
 	MapHardlinksFor []string
-	Ignore          []string
-	Remove          []string
+	Ignore          []FilterRule
+	Remove          []FilterRule
 	Label           []struct {
 		Name   string
 		Update []string
@@ -13,4 +13,33 @@ type FilterConfiguration struct {
 		Mode   string
 		Update []string
 	}
+	// order in which torrents are evaluated, by name unless configured
+	OrderBy *SortConfiguration `koanf:"order_by"`
+}
+
+// FilterRule is an ignore or remove expression, configured as either a plain string or an object.
+type FilterRule struct {
+	Name        string
+	Description string
+	// rules are enabled unless explicitly disabled
+	Enabled *bool
+	Expr    string
+}
+
+// UnmarshalText allows rules to be configured as plain expression strings.
+func (r *FilterRule) UnmarshalText(text []byte) error {
+	r.Expr = string(text)
+	return nil
+}
+
+func (r FilterRule) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+func (r FilterRule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return r.Expr
 }
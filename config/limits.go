@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+)
+
+// RemovalLimitsConfiguration aborts the removal phase of clean when exceeded, unless forced.
+type RemovalLimitsConfiguration struct {
+	// limits of all torrents of the client
+	RemovalLimit `koanf:",squash"`
+	// limits applied to every tracker
+	Tracker *RemovalLimit `koanf:"tracker"`
+	// limits of individual trackers, used instead of tracker
+	Trackers []TrackerRemovalLimit `koanf:"trackers"`
+}
+
+// RemovalLimit holds the maximum removals of a run, zero values are not limited.
+type RemovalLimit struct {
+	MaxTorrents int      `koanf:"max_torrents"`
+	MaxBytes    ByteSize `koanf:"max_bytes"`
+	// percentage of the torrents
+	MaxPercent float64 `koanf:"max_percent"`
+}
+
+// TrackerRemovalLimit holds the limits of a tracker, by TrackerName.
+type TrackerRemovalLimit struct {
+	Name         string `koanf:"name"`
+	RemovalLimit `koanf:",squash"`
+}
+
+// ByteSize is a number of bytes, configured as either a number or a string, e.g. 500GB or 1.5TiB.
+type ByteSize uint64
+
+// UnmarshalText allows sizes to be configured as human readable strings.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	v, err := humanize.ParseBytes(string(text))
+	if err != nil {
+		return fmt.Errorf("parse size: %q: %w", text, err)
+	}
+
+	*b = ByteSize(v)
+	return nil
+}
+
+// ForTracker returns the limits of a tracker, nil when not limited.
+func (c *RemovalLimitsConfiguration) ForTracker(name string) *RemovalLimit {
+	for _, t := range c.Trackers {
+		if t.Name == name {
+			return &t.RemovalLimit
+		}
+	}
+
+	return c.Tracker
+}
@@ -0,0 +1,6 @@
+package config
+
+type MetricsConfiguration struct {
+	// directory of the node_exporter textfile collector metrics are written to after each run (disabled when empty)
+	TextfileDirectory string `koanf:"textfile_directory"`
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"time"
+)
+
+// OrphanConfiguration holds the paths orphan must never remove.
+type OrphanConfiguration struct {
+	// paths modified more recently are kept, e.g. files still being written
+	MinAge time.Duration `koanf:"min_age"`
+	// glob patterns matched against the path relative to the download path and each of its names
+	Exclude []string `koanf:"exclude"`
+	// regular expressions matched against the path relative to the download path
+	ExcludeRegex []string `koanf:"exclude_regex"`
+	// file extensions, e.g. .nfo
+	IgnoreExtensions []string `koanf:"ignore_extensions"`
+}
+
+// DownloadPathConfiguration is a folder scanned by orphan, configured as either a path or a path with its own
+// mapping of client paths to local paths.
+type DownloadPathConfiguration struct {
+	Path    string            `koanf:"path"`
+	Mapping map[string]string `koanf:"mapping"`
+}
+
+// UnmarshalText allows download paths to be configured as plain path strings.
+func (d *DownloadPathConfiguration) UnmarshalText(text []byte) error {
+	d.Path = string(text)
+	return nil
+}
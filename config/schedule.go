@@ -0,0 +1,5 @@
+package config
+
+// ScheduleConfiguration maps a command (clean, relabel, retag or orphan) to the interval (e.g. 6h)
+// or cron expression (e.g. "0 */6 * * *") it should be run on by the daemon.
+type ScheduleConfiguration map[string]string
@@ -0,0 +1,8 @@
+package config
+
+type ServerConfiguration struct {
+	// address the daemon http server listens on, e.g. :7337 (disabled when empty)
+	Listen string `koanf:"listen"`
+	// required in the X-API-Key header (or apikey query parameter) of requests when set
+	ApiKey string `koanf:"api_key"`
+}
@@ -0,0 +1,14 @@
+package config
+
+// SortConfiguration orders torrents by the result of an expression, e.g. a field name.
+type SortConfiguration struct {
+	Expr string
+	// asc (default) or desc
+	Order string
+}
+
+// UnmarshalText allows sorts to be configured as a plain expression string, sorted ascending.
+func (s *SortConfiguration) UnmarshalText(text []byte) error {
+	s.Expr = string(text)
+	return nil
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"math"
 	"strings"
+	"sync"
 
 	"github.com/autobrr/tqm/sliceutils"
 	"github.com/autobrr/tqm/tracker"
@@ -27,6 +29,7 @@ type Torrent struct {
 	Path            string   `json:"Path"`
 	TotalBytes      int64    `json:"TotalBytes"`
 	DownloadedBytes int64    `json:"DownloadedBytes"`
+	UploadedBytes   int64    `json:"UploadedBytes"`
 	State           string   `json:"State"`
 	Files           []string `json:"Files"`
 	Tags            []string `json:"Tags"`
@@ -53,9 +56,41 @@ type Torrent struct {
 
 	// set by command
 	HardlinkedOutsideClient bool `json:"-"`
+	// hours since the torrent was first seen unregistered, 0 when registered
+	UnregisteredSinceHours float32 `json:"UnregisteredSinceHours"`
+	// derived from the stats history of the torrent, 0 until recorded
+	UploadedLast24h     int64   `json:"UploadedLast24h"`
+	UploadedLast7d      int64   `json:"UploadedLast7d"`
+	RatioGainPerDay     float32 `json:"RatioGainPerDay"`
+	DaysSinceLastUpload float32 `json:"DaysSinceLastUpload"`
+	StatsHistoryDays    float32 `json:"StatsHistoryDays"`
+	// set by CacheUnregistered, nil when the check is not cached
+	unregistered *unregisteredCheck
+}
+
+// unregisteredCheck holds the result of checking whether a torrent is unregistered, shared by copies of the torrent.
+type unregisteredCheck struct {
+	once         sync.Once
+	unregistered bool
+	err          error
+}
+
+// CacheUnregistered makes the unregistered check of each torrent run at most once, the first time it is needed.
+func CacheUnregistered(torrents map[string]Torrent) {
+	for h, t := range torrents {
+		t.unregistered = new(unregisteredCheck)
+		torrents[h] = t
+	}
 }
 
 func (t *Torrent) IsUnregistered() bool {
+	ur, _ := t.CheckUnregistered()
+	return ur
+}
+
+// HasUnregisteredStatus returns whether the tracker status of the torrent is a known unregistered status,
+// without checking the tracker api.
+func (t *Torrent) HasUnregisteredStatus() bool {
 	if t.TrackerStatus == "" || strings.Contains(t.TrackerStatus, "Tracker is down") {
 		return false
 	}
@@ -69,26 +104,21 @@ func (t *Torrent) IsUnregistered() bool {
 		}
 	}
 
-	// check tracker api (if available)
-	if tr := tracker.Get(t.TrackerName); tr != nil {
-		tt := &tracker.Torrent{
-			Hash:            t.Hash,
-			Name:            t.Name,
-			TotalBytes:      t.TotalBytes,
-			DownloadedBytes: t.DownloadedBytes,
-			State:           t.State,
-			Downloaded:      t.Downloaded,
-			Seeding:         t.Seeding,
-			TrackerName:     t.TrackerName,
-			TrackerStatus:   t.State,
-		}
+	return false
+}
 
-		if err, ur := tr.IsUnregistered(tt); err == nil {
-			return ur
-		}
+// CheckUnregistered returns whether the torrent is unregistered, checking the tracker api (if available)
+// when the tracker status is not conclusive. An error is returned when the tracker api request failed.
+func (t *Torrent) CheckUnregistered() (bool, error) {
+	if t.unregistered == nil {
+		return t.checkUnregistered()
 	}
 
-	return false
+	t.unregistered.once.Do(func() {
+		t.unregistered.unregistered, t.unregistered.err = t.checkUnregistered()
+	})
+
+	return t.unregistered.unregistered, t.unregistered.err
 }
 
 func (t *Torrent) HasAllTags(tags ...string) bool {
@@ -114,3 +144,38 @@ func (t *Torrent) HasAnyTag(tags ...string) bool {
 func (t *Torrent) Log(n float64) float64 {
 	return math.Log(n)
 }
+
+func (t *Torrent) checkUnregistered() (bool, error) {
+	if t.TrackerStatus == "" || strings.Contains(t.TrackerStatus, "Tracker is down") {
+		return false, nil
+	}
+
+	if t.HasUnregisteredStatus() {
+		return true, nil
+	}
+
+	// check tracker api (if available)
+	tr := tracker.Get(t.TrackerName)
+	if tr == nil {
+		return false, nil
+	}
+
+	tt := &tracker.Torrent{
+		Hash:            t.Hash,
+		Name:            t.Name,
+		TotalBytes:      t.TotalBytes,
+		DownloadedBytes: t.DownloadedBytes,
+		State:           t.State,
+		Downloaded:      t.Downloaded,
+		Seeding:         t.Seeding,
+		TrackerName:     t.TrackerName,
+		TrackerStatus:   t.State,
+	}
+
+	err, ur := tr.IsUnregistered(tt)
+	if err != nil {
+		return false, fmt.Errorf("check tracker: %w", err)
+	}
+
+	return ur, nil
+}
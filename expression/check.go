@@ -6,39 +6,39 @@ import (
 	"github.com/autobrr/tqm/config"
 
 	"github.com/expr-lang/expr"
-	"github.com/expr-lang/expr/vm"
 )
 
-func CheckTorrentSingleMatch(t *config.Torrent, exp []*vm.Program) (bool, error) {
+// CheckTorrentSingleMatch returns the first expression matching the torrent, or nil when none match.
+func CheckTorrentSingleMatch(t *config.Torrent, exp []*Expression) (*Expression, error) {
 	for _, expression := range exp {
-		result, err := expr.Run(expression, t)
+		result, err := expr.Run(expression.Program, t)
 		if err != nil {
-			return false, fmt.Errorf("check expression: %w", err)
+			return nil, fmt.Errorf("check expression: %q: %w", expression.Text, err)
 		}
 
 		expResult, ok := result.(bool)
 		if !ok {
-			return false, fmt.Errorf("type assert expression result: %w", err)
+			return nil, fmt.Errorf("type assert expression result: %q: %w", expression.Text, err)
 		}
 
 		if expResult {
-			return true, nil
+			return expression, nil
 		}
 	}
 
-	return false, nil
+	return nil, nil
 }
 
-func CheckTorrentAllMatch(t *config.Torrent, exp []*vm.Program) (bool, error) {
+func CheckTorrentAllMatch(t *config.Torrent, exp []*Expression) (bool, error) {
 	for _, expression := range exp {
-		result, err := expr.Run(expression, t)
+		result, err := expr.Run(expression.Program, t)
 		if err != nil {
-			return false, fmt.Errorf("check expression: %w", err)
+			return false, fmt.Errorf("check expression: %q: %w", expression.Text, err)
 		}
 
 		expResult, ok := result.(bool)
 		if !ok {
-			return false, fmt.Errorf("type assert expression result: %w", err)
+			return false, fmt.Errorf("type assert expression result: %q: %w", expression.Text, err)
 		}
 
 		if !expResult {
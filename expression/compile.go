@@ -13,58 +13,92 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 	exp := new(Expressions)
 
 	// compile ignores
-	for _, ignoreExpr := range filter.Ignore {
-		program, err := expr.Compile(ignoreExpr, expr.Env(exprEnv), expr.AsBool())
+	for i, rule := range filter.Ignore {
+		e, err := compileRule(i, rule, exprEnv)
 		if err != nil {
-			return nil, fmt.Errorf("compile ignore expression: %q: %w", ignoreExpr, err)
+			return nil, fmt.Errorf("compile ignore expression: %w", err)
+		} else if e != nil {
+			exp.Ignores = append(exp.Ignores, e)
 		}
-
-		exp.Ignores = append(exp.Ignores, program)
 	}
 
 	// compile removes
-	for _, removeExpr := range filter.Remove {
-		program, err := expr.Compile(removeExpr, expr.Env(exprEnv), expr.AsBool())
+	for i, rule := range filter.Remove {
+		e, err := compileRule(i, rule, exprEnv)
 		if err != nil {
-			return nil, fmt.Errorf("compile remove expression: %q: %w", removeExpr, err)
+			return nil, fmt.Errorf("compile remove expression: %w", err)
+		} else if e != nil {
+			exp.Removes = append(exp.Removes, e)
 		}
-
-		exp.Removes = append(exp.Removes, program)
 	}
 
 	// compile labels
-	for _, labelExpr := range filter.Label {
-		le := &LabelExpression{Name: labelExpr.Name}
+	for i, labelExpr := range filter.Label {
+		le := &LabelExpression{Index: i, Name: labelExpr.Name}
 
 		// compile updates
-		for _, updateExpr := range labelExpr.Update {
+		for j, updateExpr := range labelExpr.Update {
 			program, err := expr.Compile(updateExpr, expr.Env(exprEnv), expr.AsBool())
 			if err != nil {
 				return nil, fmt.Errorf("compile label update expression: %v: %q: %w", labelExpr.Name, updateExpr, err)
 			}
 
-			le.Updates = append(le.Updates, program)
+			le.Updates = append(le.Updates, &Expression{Index: j, Text: updateExpr, Program: program})
 		}
 
 		exp.Labels = append(exp.Labels, le)
 	}
 
 	// compile tags
-	for _, tagExpr := range filter.Tag {
-		le := &TagExpression{Name: tagExpr.Name, Mode: tagExpr.Mode}
+	for i, tagExpr := range filter.Tag {
+		le := &TagExpression{Index: i, Name: tagExpr.Name, Mode: tagExpr.Mode}
 
 		// compile updates
-		for _, updateExpr := range tagExpr.Update {
+		for j, updateExpr := range tagExpr.Update {
 			program, err := expr.Compile(updateExpr, expr.Env(exprEnv), expr.AsBool())
 			if err != nil {
 				return nil, fmt.Errorf("compile tag update expression: %v: %q: %w", tagExpr.Name, updateExpr, err)
 			}
 
-			le.Updates = append(le.Updates, program)
+			le.Updates = append(le.Updates, &Expression{Index: j, Text: updateExpr, Program: program})
 		}
 
 		exp.Tags = append(exp.Tags, le)
 	}
 
+	// compile order
+	if filter.OrderBy != nil {
+		s, err := CompileSort(*filter.OrderBy)
+		if err != nil {
+			return nil, fmt.Errorf("compile order_by: %w", err)
+		}
+
+		exp.OrderBy = s
+	}
+
 	return exp, nil
 }
+
+// compileRule compiles an ignore or remove rule, returning nil for disabled rules.
+func compileRule(index int, rule config.FilterRule, env *config.Torrent) (*Expression, error) {
+	if !rule.IsEnabled() {
+		return nil, nil
+	}
+
+	if rule.Expr == "" {
+		return nil, fmt.Errorf("%d: %q: expr must be set", index, rule.Name)
+	}
+
+	program, err := expr.Compile(rule.Expr, expr.Env(env), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", rule.String(), err)
+	}
+
+	return &Expression{
+		Index:       index,
+		Name:        rule.Name,
+		Description: rule.Description,
+		Text:        rule.Expr,
+		Program:     program,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package expression
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/autobrr/tqm/config"
+
+	"github.com/expr-lang/expr/ast"
+)
+
+/* Vars */
+
+var (
+	// torrent fields available to expressions, excluding functions
+	torrentFields = func() map[string]bool {
+		fields := make(map[string]bool)
+
+		t := reflect.TypeOf(config.Torrent{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.IsExported() && f.Type.Kind() != reflect.Func {
+				fields[f.Name] = true
+			}
+		}
+
+		return fields
+	}()
+)
+
+/* Structs */
+
+type fieldVisitor struct {
+	seen   map[string]bool
+	fields []string
+}
+
+func (v *fieldVisitor) Visit(node *ast.Node) {
+	n, ok := (*node).(*ast.IdentifierNode)
+	if !ok || !torrentFields[n.Value] || v.seen[n.Value] {
+		return
+	}
+
+	v.seen[n.Value] = true
+	v.fields = append(v.fields, n.Value)
+}
+
+/* Public */
+
+// Fields returns the torrent fields referenced by the expression, in order of appearance.
+func (e *Expression) Fields() []string {
+	return fieldsOf([]*Expression{e})
+}
+
+// Fields returns the torrent fields referenced by the update expressions.
+func (le *LabelExpression) Fields() []string {
+	return fieldsOf(le.Updates)
+}
+
+// Fields returns the torrent fields referenced by the update expressions.
+func (te *TagExpression) Fields() []string {
+	return fieldsOf(te.Updates)
+}
+
+// FieldValue returns the value of a torrent field.
+func FieldValue(t *config.Torrent, field string) interface{} {
+	if !torrentFields[field] {
+		return nil
+	}
+
+	return reflect.ValueOf(t).Elem().FieldByName(field).Interface()
+}
+
+// CompileExpression compiles a single ad-hoc expression.
+func CompileExpression(text string) (*Expression, error) {
+	return compileRule(0, config.FilterRule{Expr: text}, &config.Torrent{})
+}
+
+// References returns whether any of the expressions, or the order, reference the torrent field.
+func (exp *Expressions) References(field string) bool {
+	exps := append(append([]*Expression{}, exp.Ignores...), exp.Removes...)
+	for _, le := range exp.Labels {
+		exps = append(exps, le.Updates...)
+	}
+	for _, te := range exp.Tags {
+		exps = append(exps, te.Updates...)
+	}
+
+	return slices.Contains(fieldsOf(exps), field) || exp.OrderBy.References(field)
+}
+
+// References returns whether the sort expression references the torrent field.
+func (s *Sort) References(field string) bool {
+	if s == nil {
+		return false
+	}
+
+	v := &fieldVisitor{seen: make(map[string]bool)}
+	node := s.Program.Node()
+	ast.Walk(&node, v)
+
+	return v.seen[field]
+}
+
+/* Private */
+
+func fieldsOf(exps []*Expression) []string {
+	v := &fieldVisitor{seen: make(map[string]bool)}
+	for _, e := range exps {
+		node := e.Program.Node()
+		ast.Walk(&node, v)
+	}
+
+	return v.fields
+}
@@ -0,0 +1,148 @@
+package expression
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/autobrr/tqm/config"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+/* Struct */
+
+// Sort orders torrents by the result of an expression, numbers, strings and booleans are supported.
+type Sort struct {
+	Text    string
+	Desc    bool
+	Program *vm.Program
+}
+
+/* Initializer */
+
+func CompileSort(cfg config.SortConfiguration) (*Sort, error) {
+	s := &Sort{Text: cfg.Expr}
+
+	switch strings.ToLower(cfg.Order) {
+	case "", "asc":
+	case "desc":
+		s.Desc = true
+	default:
+		return nil, fmt.Errorf("unsupported order: %q", cfg.Order)
+	}
+
+	program, err := expr.Compile(cfg.Expr, expr.Env(&config.Torrent{}))
+	if err != nil {
+		return nil, fmt.Errorf("compile sort expression: %q: %w", cfg.Expr, err)
+	}
+
+	s.Program = program
+	return s, nil
+}
+
+/* Public */
+
+func (s *Sort) String() string {
+	if s.Desc {
+		return s.Text + " desc"
+	}
+
+	return s.Text + " asc"
+}
+
+// SortTorrents stably sorts items by the result of the sort expression for the torrent of each item.
+func SortTorrents[T any](s *Sort, items []T, torrent func(T) *config.Torrent) error {
+	type keyed struct {
+		item T
+		key  interface{}
+	}
+
+	keys := make([]keyed, 0, len(items))
+	for _, item := range items {
+		t := torrent(item)
+
+		key, err := expr.Run(s.Program, t)
+		if err != nil {
+			return fmt.Errorf("run sort expression: %q: %v: %w", s.Text, t.Hash, err)
+		}
+
+		key, err = normalizeKey(key)
+		if err != nil {
+			return fmt.Errorf("sort expression: %q: %w", s.Text, err)
+		}
+
+		keys = append(keys, keyed{item: item, key: key})
+	}
+
+	var err error
+	slices.SortStableFunc(keys, func(a, b keyed) int {
+		c, cerr := compareKeys(a.key, b.key)
+		if cerr != nil && err == nil {
+			err = fmt.Errorf("sort expression: %q: %w", s.Text, cerr)
+		}
+
+		if s.Desc {
+			return -c
+		}
+		return c
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, k := range keys {
+		items[i] = k.item
+	}
+
+	return nil
+}
+
+/* Private */
+
+func normalizeKey(v interface{}) (interface{}, error) {
+	switch vv := v.(type) {
+	case int:
+		return float64(vv), nil
+	case int32:
+		return float64(vv), nil
+	case int64:
+		return float64(vv), nil
+	case uint64:
+		return float64(vv), nil
+	case float32:
+		return float64(vv), nil
+	case float64, string, bool:
+		return vv, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type: %T", v)
+	}
+}
+
+func compareKeys(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return cmp.Compare(av, bv), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return cmp.Compare(av, bv), nil
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0, nil
+			case !av:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("mismatched result types: %T and %T", a, b)
+}
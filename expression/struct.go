@@ -1,21 +1,109 @@
 package expression
 
-import "github.com/expr-lang/expr/vm"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr/vm"
+)
 
 type Expressions struct {
-	Ignores []*vm.Program
-	Removes []*vm.Program
+	Ignores []*Expression
+	Removes []*Expression
 	Labels  []*LabelExpression
 	Tags    []*TagExpression
+	// order in which torrents are evaluated, nil when not configured
+	OrderBy *Sort
+}
+
+// Expression is a compiled expression along with its position and source text within the filter.
+type Expression struct {
+	Index int
+	// name and description of named ignore and remove rules
+	Name        string
+	Description string
+	Text        string
+	Program     *vm.Program
 }
 
 type LabelExpression struct {
+	Index   int
 	Name    string
-	Updates []*vm.Program
+	Updates []*Expression
 }
 
 type TagExpression struct {
+	Index   int
 	Name    string
 	Mode    string
-	Updates []*vm.Program
+	Updates []*Expression
+}
+
+// Match identifies the expression responsible for a filter decision.
+type Match struct {
+	// ignore, remove, label or tag
+	Kind string `json:"kind"`
+	// name of the rule, label or tag
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Index       int    `json:"index"`
+	Expression  string `json:"expression"`
+}
+
+/* Match */
+
+func (e *Expression) Match(kind string) *Match {
+	return &Match{
+		Kind:        kind,
+		Name:        e.Name,
+		Description: e.Description,
+		Index:       e.Index,
+		Expression:  e.Text,
+	}
+}
+
+func (le *LabelExpression) Match() *Match {
+	return &Match{
+		Kind:       "label",
+		Name:       le.Name,
+		Index:      le.Index,
+		Expression: joinUpdates(le.Updates),
+	}
+}
+
+func (te *TagExpression) Match() *Match {
+	return &Match{
+		Kind:       "tag",
+		Name:       te.Name,
+		Index:      te.Index,
+		Expression: joinUpdates(te.Updates),
+	}
+}
+
+func (m *Match) String() string {
+	if m == nil {
+		return ""
+	}
+
+	if m.Name != "" {
+		return fmt.Sprintf("%s[%d] %s: %s", m.Kind, m.Index, m.Name, m.Expression)
+	}
+
+	return fmt.Sprintf("%s[%d]: %s", m.Kind, m.Index, m.Expression)
+}
+
+/* Private */
+
+// joinUpdates returns the source text of update expressions, which must all match.
+func joinUpdates(updates []*Expression) string {
+	if len(updates) == 1 {
+		return updates[0].Text
+	}
+
+	texts := make([]string, 0, len(updates))
+	for _, u := range updates {
+		texts = append(texts, "("+u.Text+")")
+	}
+
+	return strings.Join(texts, " && ")
 }
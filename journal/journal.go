@@ -0,0 +1,118 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/* Const */
+
+const (
+	ActionRelabel = "relabel"
+	ActionRetag   = "retag"
+
+	// lines of the journal are limited to 1MB
+	maxLineSize = 1024 * 1024
+)
+
+/* Struct */
+
+// Entry is a label or tag change applied to a torrent, with the state it had before.
+type Entry struct {
+	RunID   string    `json:"run_id"`
+	Time    time.Time `json:"time"`
+	Client  string    `json:"client"`
+	Command string    `json:"command"`
+	Action  string    `json:"action"`
+	Hash    string    `json:"hash"`
+	Name    string    `json:"name"`
+	Rule    string    `json:"rule,omitempty"`
+
+	// relabel
+	OldLabel string `json:"old_label"`
+	NewLabel string `json:"new_label"`
+	Hardlink bool   `json:"hardlink,omitempty"`
+
+	// retag
+	OldTags []string `json:"old_tags"`
+	NewTags []string `json:"new_tags"`
+}
+
+/* Public */
+
+// NewRunID returns an identifier for a run started at the given time.
+func NewRunID(started time.Time) string {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		// fall back to the sub-second part of the start
+		return started.Format("20060102-150405.000000")
+	}
+
+	return started.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Append adds entries to the end of the journal, creating it when missing.
+func Append(path string, entries ...Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	// one entry per line
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+
+	return f.Close()
+}
+
+// Load returns the entries of a run in the order they were applied.
+func Load(path string, runID string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("unmarshal line %d: %w", line, err)
+		}
+
+		if e.RunID == runID {
+			entries = append(entries, e)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* Const */
+
+const (
+	Counter   = "counter"
+	Gauge     = "gauge"
+	Histogram = "histogram"
+)
+
+/* Structs */
+
+type definition struct {
+	name string
+	typ  string
+	help string
+	// upper bounds of the buckets of a histogram
+	buckets []float64
+}
+
+type series struct {
+	labels map[string]string
+	value  float64
+	// cumulative counts of the buckets of a histogram, its sum is kept in value
+	counts []uint64
+	count  uint64
+}
+
+type metric struct {
+	definition
+	series map[string]*series
+}
+
+/* Vars */
+
+var (
+	mtx     sync.Mutex
+	metrics = make(map[string]*metric)
+	order   []string
+
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+/* Public */
+
+// Register registers a metric, registering an already registered metric is a no-op.
+func Register(name string, typ string, help string) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if _, ok := metrics[name]; ok {
+		return
+	}
+
+	metrics[name] = &metric{
+		definition: definition{name: name, typ: typ, help: help},
+		series:     make(map[string]*series),
+	}
+	order = append(order, name)
+}
+
+// RegisterHistogram registers a histogram with the upper bounds of its buckets in increasing order,
+// registering an already registered metric is a no-op.
+func RegisterHistogram(name string, help string, buckets []float64) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if _, ok := metrics[name]; ok {
+		return
+	}
+
+	metrics[name] = &metric{
+		definition: definition{name: name, typ: Histogram, help: help, buckets: buckets},
+		series:     make(map[string]*series),
+	}
+	order = append(order, name)
+}
+
+// Observe adds the observation v to the series of a histogram identified by labels (key, value pairs).
+func Observe(name string, v float64, labels ...string) {
+	update(name, labels, func(s *series) {
+		s.value += v
+		s.count++
+		for i, le := range metrics[name].buckets {
+			if v <= le {
+				s.counts[i]++
+			}
+		}
+	})
+}
+
+// Add adds v to the series of a metric identified by labels (key, value pairs).
+func Add(name string, v float64, labels ...string) {
+	update(name, labels, func(s *series) {
+		s.value += v
+	})
+}
+
+// Set sets the series of a metric identified by labels (key, value pairs) to v.
+func Set(name string, v float64, labels ...string) {
+	update(name, labels, func(s *series) {
+		s.value = v
+	})
+}
+
+// Write writes all metrics in the prometheus text exposition format,
+// adding constLabels to series that do not have these labels already.
+func Write(w io.Writer, constLabels map[string]string) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	buf := new(bytes.Buffer)
+	for _, name := range order {
+		m := metrics[name]
+		if len(m.series) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", m.name, m.typ)
+
+		keys := make([]string, 0, len(m.series))
+		for k := range m.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			s := m.series[k]
+
+			labels := make(map[string]string, len(s.labels)+len(constLabels))
+			for lk, lv := range constLabels {
+				labels[lk] = lv
+			}
+			for lk, lv := range s.labels {
+				labels[lk] = lv
+			}
+
+			if m.typ == Histogram {
+				writeHistogram(buf, m, s, labels)
+				continue
+			}
+
+			fmt.Fprintf(buf, "%s%s %s\n", m.name, formatLabels(labels),
+				strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Handler serves all metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := Write(w, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WriteTextfile atomically writes all metrics to path, to be picked up by the node_exporter textfile collector.
+func WriteTextfile(path string, constLabels map[string]string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, constLabels); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write metrics: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+/* Private */
+
+func update(name string, labels []string, fn func(*series)) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	m, ok := metrics[name]
+	if !ok {
+		return
+	}
+
+	lm := make(map[string]string, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		lm[labels[i]] = labels[i+1]
+	}
+
+	key := formatLabels(lm)
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labels: lm, counts: make([]uint64, len(m.buckets))}
+		m.series[key] = s
+	}
+
+	fn(s)
+}
+
+// writeHistogram writes the buckets, sum and count of a histogram series.
+func writeHistogram(buf *bytes.Buffer, m *metric, s *series, labels map[string]string) {
+	bucketLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		bucketLabels[k] = v
+	}
+
+	for i, le := range m.buckets {
+		bucketLabels["le"] = strconv.FormatFloat(le, 'g', -1, 64)
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", m.name, formatLabels(bucketLabels), s.counts[i])
+	}
+
+	bucketLabels["le"] = "+Inf"
+	fmt.Fprintf(buf, "%s_bucket%s %d\n", m.name, formatLabels(bucketLabels), s.count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n", m.name, formatLabels(labels), strconv.FormatFloat(s.value, 'g', -1, 64))
+	fmt.Fprintf(buf, "%s_count%s %d\n", m.name, formatLabels(labels), s.count)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+`="`+labelValueEscaper.Replace(labels[k])+`"`)
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
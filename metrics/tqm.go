@@ -0,0 +1,38 @@
+package metrics
+
+const (
+	Torrents                 = "tqm_torrents"
+	FreeSpaceGB              = "tqm_free_space_gigabytes"
+	IgnoredTorrents          = "tqm_ignored_torrents_total"
+	RemovedTorrents          = "tqm_removed_torrents_total"
+	RelabeledTorrents        = "tqm_relabeled_torrents_total"
+	RetaggedTorrents         = "tqm_retagged_torrents_total"
+	RemovedOrphans           = "tqm_removed_orphans_total"
+	Failures                 = "tqm_failures_total"
+	ReclaimedBytes           = "tqm_reclaimed_bytes_total"
+	Runs                     = "tqm_runs_total"
+	RunDuration              = "tqm_run_duration_seconds"
+	LastRunTimestamp         = "tqm_last_run_timestamp_seconds"
+	TrackerApiRequests       = "tqm_tracker_api_requests_total"
+	TrackerApiErrors         = "tqm_tracker_api_errors_total"
+	TrackerApiRequestSeconds = "tqm_tracker_api_request_duration_seconds"
+)
+
+func init() {
+	Register(Torrents, Gauge, "Number of torrents retrieved from the client during the last run.")
+	Register(FreeSpaceGB, Gauge, "Free space in GB reported by the client during the last run.")
+	Register(IgnoredTorrents, Counter, "Number of torrents ignored by filters.")
+	Register(RemovedTorrents, Counter, "Number of torrents removed.")
+	Register(RelabeledTorrents, Counter, "Number of torrents relabeled.")
+	Register(RetaggedTorrents, Counter, "Number of torrents retagged.")
+	Register(RemovedOrphans, Counter, "Number of orphan files and folders removed.")
+	Register(Failures, Counter, "Number of torrents or orphans that failed to be processed.")
+	Register(ReclaimedBytes, Counter, "Number of bytes reclaimed by removing torrents and orphans.")
+	Register(Runs, Counter, "Number of command runs.")
+	Register(RunDuration, Gauge, "Duration of the last command run in seconds.")
+	Register(LastRunTimestamp, Gauge, "Unix timestamp of the end of the last command run.")
+	Register(TrackerApiRequests, Counter, "Number of requests made to tracker apis.")
+	Register(TrackerApiErrors, Counter, "Number of failed requests made to tracker apis.")
+	RegisterHistogram(TrackerApiRequestSeconds, "Duration of requests made to tracker apis in seconds.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30})
+}
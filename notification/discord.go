@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+/* Const */
+
+const (
+	discordMaxTitleLength       = 256
+	discordMaxDescriptionLength = 4096
+
+	discordColorSuccess = 0x2ecc71
+	discordColorFailure = 0xe74c3c
+	discordColorEvent   = 0x3498db
+)
+
+/* Struct */
+
+type Discord struct {
+	cfg  TargetConfig
+	http *http.Client
+}
+
+/* Interface */
+
+func (d *Discord) Type() string {
+	return "discord"
+}
+
+func (d *Discord) Send(m *Message) error {
+	type embed struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Color       int    `json:"color"`
+	}
+
+	type payload struct {
+		Username string  `json:"username"`
+		Embeds   []embed `json:"embeds"`
+	}
+
+	color := discordColorSuccess
+	switch {
+	case m.Failed:
+		color = discordColorFailure
+	case m.Kind == "event":
+		color = discordColorEvent
+	}
+
+	b, err := json.Marshal(payload{
+		Username: "tqm",
+		Embeds: []embed{{
+			Title:       truncate(m.Title, discordMaxTitleLength),
+			Description: truncate(m.Text, discordMaxDescriptionLength),
+			Color:       color,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.cfg.Url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return do(d.http, req, d.cfg.Headers)
+}
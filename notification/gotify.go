@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/autobrr/tqm/httputils"
+)
+
+/* Struct */
+
+// Gotify creates messages using an application token.
+type Gotify struct {
+	cfg  TargetConfig
+	http *http.Client
+}
+
+/* Interface */
+
+func (g *Gotify) Type() string {
+	return "gotify"
+}
+
+func (g *Gotify) Send(m *Message) error {
+	type payload struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+
+	b, err := json.Marshal(payload{
+		Title:    m.Title,
+		Message:  m.Text,
+		Priority: g.cfg.Priority,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, httputils.Join(g.cfg.Url, "message"), bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.cfg.Token)
+
+	return do(g.http, req, g.cfg.Headers)
+}
@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+/* Structs */
+
+type Torrent struct {
+	Hash        string  `json:"hash"`
+	Name        string  `json:"name"`
+	Tracker     string  `json:"tracker"`
+	Ratio       float32 `json:"ratio"`
+	SeedingDays float32 `json:"seeding_days"`
+	Bytes       int64   `json:"bytes"`
+	Rule        string  `json:"rule,omitempty"`
+}
+
+type Failure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+type Summary struct {
+	Client   string `json:"client"`
+	Command  string `json:"command"`
+	DryRun   bool   `json:"dry_run"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+
+	Torrents       int    `json:"torrents"`
+	Ignored        int    `json:"ignored"`
+	Removed        int    `json:"removed"`
+	Relabeled      int    `json:"relabeled"`
+	Retagged       int    `json:"retagged"`
+	OrphanFiles    int    `json:"orphan_files"`
+	OrphanFolders  int    `json:"orphan_folders"`
+	ReclaimedBytes uint64 `json:"reclaimed_bytes"`
+
+	RemovedTorrents []Torrent `json:"removed_torrents,omitempty"`
+	Failures        []Failure `json:"failures,omitempty"`
+}
+
+type Event struct {
+	Client  string `json:"client"`
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+
+	// torrent name or orphan path
+	Name string `json:"name"`
+	Hash string `json:"hash,omitempty"`
+
+	// relabel
+	OldLabel string `json:"old_label,omitempty"`
+	NewLabel string `json:"new_label,omitempty"`
+
+	// retag
+	AddedTags   []string `json:"added_tags,omitempty"`
+	RemovedTags []string `json:"removed_tags,omitempty"`
+
+	// orphan
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+type Message struct {
+	// summary or event
+	Kind    string   `json:"kind"`
+	Title   string   `json:"title"`
+	Text    string   `json:"text"`
+	Failed  bool     `json:"failed"`
+	Summary *Summary `json:"summary,omitempty"`
+	Event   *Event   `json:"event,omitempty"`
+}
+
+/* Summary */
+
+// Empty returns whether the run finished without any actions or failures.
+func (s *Summary) Empty() bool {
+	return s.Error == "" && len(s.Failures) == 0 && s.Removed == 0 && s.Relabeled == 0 && s.Retagged == 0 &&
+		s.OrphanFiles == 0 && s.OrphanFolders == 0
+}
+
+func (s *Summary) Message() *Message {
+	title := fmt.Sprintf("tqm %s of %s", s.Command, s.Client)
+	if s.Error != "" {
+		title += " failed"
+	} else {
+		title += " finished"
+	}
+	if s.DryRun {
+		title += " (dry-run)"
+	}
+
+	lines := make([]string, 0)
+	if s.Error != "" {
+		lines = append(lines, "Error: "+s.Error)
+	}
+
+	switch s.Command {
+	case "clean":
+		lines = append(lines, fmt.Sprintf("Removed %d of %d torrents (%d ignored), reclaimed %s", s.Removed,
+			s.Torrents, s.Ignored, humanize.IBytes(s.ReclaimedBytes)))
+	case "relabel":
+		lines = append(lines, fmt.Sprintf("Relabeled %d of %d torrents", s.Relabeled, s.Torrents))
+	case "retag":
+		lines = append(lines, fmt.Sprintf("Retagged %d of %d torrents", s.Retagged, s.Torrents))
+	case "orphan":
+		lines = append(lines, fmt.Sprintf("Removed %d orphan files and %d folders, reclaimed %s", s.OrphanFiles,
+			s.OrphanFolders, humanize.IBytes(s.ReclaimedBytes)))
+	}
+
+	if len(s.RemovedTorrents) > 0 {
+		lines = append(lines, "", "Removed:")
+		for i, t := range s.RemovedTorrents {
+			if i == maxListedItems {
+				lines = append(lines, fmt.Sprintf("... and %d more", len(s.RemovedTorrents)-i))
+				break
+			}
+
+			line := fmt.Sprintf("- %s (%s) - ratio: %.2f / seed days: %.1f / %s", t.Name, t.Tracker, t.Ratio,
+				t.SeedingDays, humanize.IBytes(uint64(t.Bytes)))
+			if t.Rule != "" {
+				line += " / rule: " + t.Rule
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	if len(s.Failures) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Failures (%d):", len(s.Failures)))
+		for i, f := range s.Failures {
+			if i == maxListedItems {
+				lines = append(lines, fmt.Sprintf("... and %d more", len(s.Failures)-i))
+				break
+			}
+
+			lines = append(lines, fmt.Sprintf("- %s: %s", f.Name, f.Error))
+		}
+	}
+
+	return &Message{
+		Kind:    "summary",
+		Title:   title,
+		Text:    strings.Join(lines, "\n"),
+		Failed:  s.Error != "" || len(s.Failures) > 0,
+		Summary: s,
+	}
+}
+
+/* Event */
+
+func (e *Event) Message() *Message {
+	var title, text string
+
+	switch e.Command {
+	case "relabel":
+		title = fmt.Sprintf("Relabeled torrent on %s", e.Client)
+		text = fmt.Sprintf("%s\n%q -> %q", e.Name, e.OldLabel, e.NewLabel)
+	case "retag":
+		title = fmt.Sprintf("Retagged torrent on %s", e.Client)
+		text = e.Name
+		if len(e.AddedTags) > 0 {
+			text += "\nAdded: " + strings.Join(e.AddedTags, ", ")
+		}
+		if len(e.RemovedTags) > 0 {
+			text += "\nRemoved: " + strings.Join(e.RemovedTags, ", ")
+		}
+	case "orphan":
+		title = fmt.Sprintf("Removed orphan on %s", e.Client)
+		text = e.Name
+		if e.Bytes > 0 {
+			text += fmt.Sprintf(" (%s)", humanize.IBytes(uint64(e.Bytes)))
+		}
+	default:
+		title = fmt.Sprintf("tqm %s on %s", e.Command, e.Client)
+		text = e.Name
+	}
+
+	if e.DryRun {
+		title += " (dry-run)"
+	}
+
+	return &Message{
+		Kind:  "event",
+		Title: title,
+		Text:  text,
+		Event: e,
+	}
+}
@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/logger"
+	"github.com/autobrr/tqm/runtime"
+	"github.com/autobrr/tqm/sliceutils"
+
+	"github.com/sirupsen/logrus"
+)
+
+/* Const */
+
+const (
+	// maximum number of removed torrents / failures listed in a summary text
+	maxListedItems = 25
+)
+
+/* Structs */
+
+type Config struct {
+	// commands whose individual actions are sent as events (relabel, retag and orphan)
+	Events []string `koanf:"events"`
+	// do not send summaries of runs without any actions or failures
+	SkipEmpty bool `koanf:"skip_empty"`
+
+	Targets []TargetConfig `koanf:"targets"`
+}
+
+type TargetConfig struct {
+	// discord, webhook, ntfy or gotify
+	Type string `koanf:"type"`
+	Url  string `koanf:"url"`
+	// ntfy access token or gotify application token
+	Token string `koanf:"token"`
+	// ntfy and gotify message priority
+	Priority int `koanf:"priority"`
+	// additional request headers
+	Headers map[string]string `koanf:"headers"`
+}
+
+type Target interface {
+	Type() string
+	Send(m *Message) error
+}
+
+type Notifier struct {
+	log     *logrus.Entry
+	cfg     Config
+	targets []Target
+}
+
+/* Initializer */
+
+func New(name string, cfg Config) (*Notifier, error) {
+	n := &Notifier{
+		log: logger.GetLogger("notification"),
+		cfg: cfg,
+	}
+
+	h := &http.Client{
+		Timeout: 15 * time.Second,
+	}
+
+	for i, tc := range cfg.Targets {
+		if tc.Url == "" {
+			return nil, fmt.Errorf("target %d of %q: url must be set", i, name)
+		}
+
+		switch strings.ToLower(tc.Type) {
+		case "discord":
+			n.targets = append(n.targets, &Discord{cfg: tc, http: h})
+		case "webhook":
+			n.targets = append(n.targets, &Webhook{cfg: tc, http: h})
+		case "ntfy":
+			n.targets = append(n.targets, &Ntfy{cfg: tc, http: h})
+		case "gotify":
+			n.targets = append(n.targets, &Gotify{cfg: tc, http: h})
+		default:
+			return nil, fmt.Errorf("target %d of %q: unsupported type: %q", i, name, tc.Type)
+		}
+	}
+
+	return n, nil
+}
+
+/* Public */
+
+// Enabled returns whether any targets are configured.
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.targets) > 0
+}
+
+// EventsEnabled returns whether the individual actions of command should be sent as events.
+func (n *Notifier) EventsEnabled(command string) bool {
+	return n.Enabled() && sliceutils.StringSliceContains(n.cfg.Events, command, true)
+}
+
+// SendSummary sends the summary of a run to all targets.
+func (n *Notifier) SendSummary(s *Summary) error {
+	if !n.Enabled() {
+		return nil
+	}
+
+	if n.cfg.SkipEmpty && s.Empty() {
+		n.log.Debugf("Skipping summary of %s for %s without any actions", s.Command, s.Client)
+		return nil
+	}
+
+	return n.send(s.Message())
+}
+
+// SendEvent sends an individual action to all targets, when events are enabled for its command.
+func (n *Notifier) SendEvent(e *Event) error {
+	if !n.EventsEnabled(e.Command) {
+		return nil
+	}
+
+	return n.send(e.Message())
+}
+
+/* Private */
+
+func (n *Notifier) send(m *Message) error {
+	var errs []error
+	for _, t := range n.targets {
+		if err := t.Send(m); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Type(), err))
+			continue
+		}
+
+		n.log.Tracef("Sent %q to %s", m.Title, t.Type())
+	}
+
+	return errors.Join(errs...)
+}
+
+// truncate shortens text to at most max characters.
+func truncate(text string, max int) string {
+	r := []rune(text)
+	if len(r) <= max {
+		return text
+	}
+
+	return string(r[:max-1]) + "…"
+}
+
+// do sends req with the additional headers, treating non-2xx responses as errors.
+func do(h *http.Client, req *http.Request, headers map[string]string) error {
+	req.Header.Set("User-Agent", "tqm/"+runtime.Version)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+/* Fake */
+
+// request is a request received by a fake target.
+type request struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newTarget starts a fake notification target responding with status, returning its url and received requests.
+func newTarget(t *testing.T, status int) (string, *[]request) {
+	t.Helper()
+
+	requests := make([]request, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{method: r.Method, path: r.URL.Path, header: r.Header, body: body})
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("target response"))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL, &requests
+}
+
+func newNotifier(t *testing.T, cfg Config) *Notifier {
+	t.Helper()
+
+	n, err := New("test", cfg)
+	if err != nil {
+		t.Fatalf("new notifier: %v", err)
+	}
+
+	return n
+}
+
+func testSummary() *Summary {
+	return &Summary{
+		Client:         "qbt",
+		Command:        "clean",
+		Duration:       "1s",
+		Torrents:       10,
+		Ignored:        2,
+		Removed:        1,
+		ReclaimedBytes: 1073741824,
+		RemovedTorrents: []Torrent{
+			{Hash: "abc", Name: "Some.Torrent", Tracker: "example.com", Ratio: 2, SeedingDays: 14, Bytes: 1073741824,
+				Rule: "remove[0] seeded"},
+		},
+		Failures: []Failure{
+			{Name: "Other.Torrent", Error: "torrent was not removed"},
+		},
+	}
+}
+
+/* Tests */
+
+func TestDiscord(t *testing.T) {
+	url, requests := newTarget(t, http.StatusNoContent)
+	n := newNotifier(t, Config{Targets: []TargetConfig{
+		{Type: "discord", Url: url + "/api/webhooks/1/token", Headers: map[string]string{"X-Extra": "value"}},
+	}})
+
+	if err := n.SendSummary(testSummary()); err != nil {
+		t.Fatalf("send summary: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(*requests))
+	}
+	r := (*requests)[0]
+
+	if r.method != http.MethodPost || r.path != "/api/webhooks/1/token" {
+		t.Errorf("unexpected request: %s %s", r.method, r.path)
+	}
+	if r.header.Get("Content-Type") != "application/json" || r.header.Get("X-Extra") != "value" ||
+		!strings.HasPrefix(r.header.Get("User-Agent"), "tqm/") {
+		t.Errorf("unexpected headers: %v", r.header)
+	}
+
+	var payload struct {
+		Username string `json:"username"`
+		Embeds   []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v: %s", err, r.body)
+	}
+
+	if payload.Username != "tqm" || len(payload.Embeds) != 1 {
+		t.Fatalf("unexpected payload: %s", r.body)
+	}
+	e := payload.Embeds[0]
+	if e.Title != "tqm clean of qbt finished" {
+		t.Errorf("unexpected title: %q", e.Title)
+	}
+	if !strings.Contains(e.Description, "Removed 1 of 10 torrents (2 ignored), reclaimed 1.0 GiB") ||
+		!strings.Contains(e.Description, "- Some.Torrent (example.com)") ||
+		!strings.Contains(e.Description, "- Other.Torrent: torrent was not removed") {
+		t.Errorf("unexpected description: %q", e.Description)
+	}
+	// failures are shown in the failure color
+	if e.Color != discordColorFailure {
+		t.Errorf("expected failure color, got %#x", e.Color)
+	}
+}
+
+func TestDiscordTruncatesDescription(t *testing.T) {
+	url, requests := newTarget(t, http.StatusNoContent)
+	n := newNotifier(t, Config{Events: []string{"orphan"}, Targets: []TargetConfig{{Type: "discord", Url: url}}})
+
+	if err := n.SendEvent(&Event{Client: "qbt", Command: "orphan", Name: strings.Repeat("a", 5000)}); err != nil {
+		t.Fatalf("send event: %v", err)
+	}
+
+	var payload struct {
+		Embeds []struct {
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal((*requests)[0].body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	if n := len([]rune(payload.Embeds[0].Description)); n != discordMaxDescriptionLength {
+		t.Errorf("expected description of %d characters, got %d", discordMaxDescriptionLength, n)
+	}
+	if payload.Embeds[0].Color != discordColorEvent {
+		t.Errorf("expected event color, got %#x", payload.Embeds[0].Color)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	url, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{Events: []string{"relabel"}, Targets: []TargetConfig{
+		{Type: "webhook", Url: url + "/hook", Headers: map[string]string{"Authorization": "Bearer secret"}},
+	}})
+
+	if err := n.SendEvent(&Event{Client: "qbt", Command: "relabel", Name: "Some.Torrent", Hash: "abc",
+		OldLabel: "incoming", NewLabel: "tv", DryRun: true}); err != nil {
+		t.Fatalf("send event: %v", err)
+	}
+
+	// events of commands not enabled are not sent
+	if err := n.SendEvent(&Event{Client: "qbt", Command: "retag", Name: "Some.Torrent"}); err != nil {
+		t.Fatalf("send event: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(*requests))
+	}
+	r := (*requests)[0]
+
+	if r.method != http.MethodPost || r.path != "/hook" {
+		t.Errorf("unexpected request: %s %s", r.method, r.path)
+	}
+	if r.header.Get("Content-Type") != "application/json" || r.header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("unexpected headers: %v", r.header)
+	}
+
+	var m Message
+	if err := json.Unmarshal(r.body, &m); err != nil {
+		t.Fatalf("unmarshal message: %v: %s", err, r.body)
+	}
+
+	if m.Kind != "event" || m.Title != "Relabeled torrent on qbt (dry-run)" || m.Text != "Some.Torrent\n\"incoming\" -> \"tv\"" {
+		t.Errorf("unexpected message: %+v", m)
+	}
+	if m.Event == nil || m.Event.Hash != "abc" || m.Event.NewLabel != "tv" || !m.Event.DryRun || m.Summary != nil {
+		t.Errorf("unexpected event: %s", r.body)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	url, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{Targets: []TargetConfig{
+		{Type: "ntfy", Url: url + "/tqm", Token: "tk_secret", Priority: 4},
+	}})
+
+	if err := n.SendSummary(testSummary()); err != nil {
+		t.Fatalf("send summary: %v", err)
+	}
+
+	r := (*requests)[0]
+	if r.method != http.MethodPost || r.path != "/tqm" {
+		t.Errorf("unexpected request: %s %s", r.method, r.path)
+	}
+
+	for k, want := range map[string]string{
+		"Title":         "tqm clean of qbt finished",
+		"Tags":          "warning",
+		"Priority":      "4",
+		"Authorization": "Bearer tk_secret",
+	} {
+		if got := r.header.Get(k); got != want {
+			t.Errorf("expected header %s %q, got %q", k, want, got)
+		}
+	}
+
+	if body := string(r.body); !strings.HasPrefix(body, "Removed 1 of 10 torrents") ||
+		!strings.Contains(body, "Failures (1):") {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestNtfyWithoutOptionalHeaders(t *testing.T) {
+	url, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{Targets: []TargetConfig{{Type: "ntfy", Url: url}}})
+
+	if err := n.SendSummary(&Summary{Client: "qbt", Command: "retag", Retagged: 3, Torrents: 10}); err != nil {
+		t.Fatalf("send summary: %v", err)
+	}
+
+	r := (*requests)[0]
+	for _, k := range []string{"Tags", "Priority", "Authorization"} {
+		if v := r.header.Get(k); v != "" {
+			t.Errorf("expected no %s header, got %q", k, v)
+		}
+	}
+	if string(r.body) != "Retagged 3 of 10 torrents" {
+		t.Errorf("unexpected body: %q", r.body)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	url, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{Events: []string{"retag"}, Targets: []TargetConfig{
+		{Type: "gotify", Url: url + "/gotify/", Token: "app-token", Priority: 5},
+	}})
+
+	if err := n.SendEvent(&Event{Client: "qbt", Command: "retag", Name: "Some.Torrent",
+		AddedTags: []string{"low-seeds"}, RemovedTags: []string{"new"}}); err != nil {
+		t.Fatalf("send event: %v", err)
+	}
+
+	r := (*requests)[0]
+	if r.method != http.MethodPost || r.path != "/gotify/message" {
+		t.Errorf("unexpected request: %s %s", r.method, r.path)
+	}
+	if r.header.Get("X-Gotify-Key") != "app-token" || r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers: %v", r.header)
+	}
+
+	var payload struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v: %s", err, r.body)
+	}
+
+	if payload.Title != "Retagged torrent on qbt" || payload.Priority != 5 ||
+		payload.Message != "Some.Torrent\nAdded: low-seeds\nRemoved: new" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	failing, _ := newTarget(t, http.StatusUnauthorized)
+	working, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{Targets: []TargetConfig{
+		{Type: "gotify", Url: failing},
+		{Type: "webhook", Url: working},
+	}})
+
+	err := n.SendSummary(testSummary())
+	if err == nil {
+		t.Fatal("expected an error for the failing target")
+	}
+	if !strings.Contains(err.Error(), "gotify: unexpected status: 401 Unauthorized: target response") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// other targets are still sent to
+	if len(*requests) != 1 {
+		t.Errorf("expected the webhook to be sent to, got %d requests", len(*requests))
+	}
+}
+
+func TestSkipEmpty(t *testing.T) {
+	url, requests := newTarget(t, http.StatusOK)
+	n := newNotifier(t, Config{SkipEmpty: true, Targets: []TargetConfig{{Type: "webhook", Url: url}}})
+
+	if err := n.SendSummary(&Summary{Client: "qbt", Command: "clean", Torrents: 10}); err != nil {
+		t.Fatalf("send summary: %v", err)
+	}
+	if err := n.SendSummary(testSummary()); err != nil {
+		t.Fatalf("send summary: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected only the summary with actions to be sent, got %d requests", len(*requests))
+	}
+}
+
+func TestNewUnsupportedTarget(t *testing.T) {
+	for _, tc := range []TargetConfig{{Type: "email", Url: "smtp://localhost"}, {Type: "webhook"}} {
+		if _, err := New("test", Config{Targets: []TargetConfig{tc}}); err == nil {
+			t.Errorf("expected an error for target: %+v", tc)
+		}
+	}
+
+	n := newNotifier(t, Config{Targets: []TargetConfig{{Type: "Discord", Url: "http://localhost"}}})
+	if types := []string{n.targets[0].Type()}; !slices.Equal(types, []string{"discord"}) {
+		t.Errorf("expected target types to be case insensitive, got %v", types)
+	}
+}
@@ -0,0 +1,49 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/* Const */
+
+const (
+	// larger messages are converted to attachments by ntfy
+	ntfyMaxMessageLength = 4096
+)
+
+/* Struct */
+
+// Ntfy publishes messages to the topic url.
+type Ntfy struct {
+	cfg  TargetConfig
+	http *http.Client
+}
+
+/* Interface */
+
+func (n *Ntfy) Type() string {
+	return "ntfy"
+}
+
+func (n *Ntfy) Send(m *Message) error {
+	req, err := http.NewRequest(http.MethodPost, n.cfg.Url, strings.NewReader(truncate(m.Text, ntfyMaxMessageLength)))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Title", m.Title)
+	if m.Failed {
+		req.Header.Set("Tags", "warning")
+	}
+	if n.cfg.Priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(n.cfg.Priority))
+	}
+	if n.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.cfg.Token)
+	}
+
+	return do(n.http, req, n.cfg.Headers)
+}
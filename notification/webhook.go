@@ -0,0 +1,37 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+/* Struct */
+
+// Webhook posts messages, including the summary or event they were created from, as json.
+type Webhook struct {
+	cfg  TargetConfig
+	http *http.Client
+}
+
+/* Interface */
+
+func (w *Webhook) Type() string {
+	return "webhook"
+}
+
+func (w *Webhook) Send(m *Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.cfg.Url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return do(w.http, req, w.cfg.Headers)
+}
@@ -0,0 +1,172 @@
+package pathindex
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/autobrr/tqm/config"
+)
+
+/* Struct */
+
+// Index holds the local paths of torrent files and every folder containing them, for exact lookups.
+type Index struct {
+	files map[string]struct{}
+	dirs  map[string]struct{}
+	// paths of the client are mapped to local paths before being indexed
+	pathMapping map[string]string
+	roots       []root
+}
+
+// root is a local folder with its own mapping of client paths.
+type root struct {
+	path        string
+	pathMapping map[string]string
+}
+
+/* Initializer */
+
+func New(torrents map[string]config.Torrent, pathMapping map[string]string) *Index {
+	idx := &Index{
+		files:       make(map[string]struct{}),
+		dirs:        make(map[string]struct{}),
+		pathMapping: pathMapping,
+	}
+
+	for _, t := range torrents {
+		idx.AddTorrent(t)
+	}
+
+	return idx
+}
+
+/* Public */
+
+// AddRoot adds a local folder with its own mapping, paths of the client mapped into it by that mapping are
+// indexed within it. Roots must be added before the torrents.
+func (i *Index) AddRoot(path string, pathMapping map[string]string) {
+	i.roots = append(i.roots, root{
+		path:        filepath.Clean(path),
+		pathMapping: pathMapping,
+	})
+}
+
+// AddTorrent indexes the files of a torrent and their parent folders.
+func (i *Index) AddTorrent(t config.Torrent) {
+	for _, f := range t.Files {
+		for _, p := range i.LocalPaths(f) {
+			i.AddFile(p)
+		}
+	}
+}
+
+// AddFile indexes a local file path and its parent folders.
+func (i *Index) AddFile(path string) {
+	path = filepath.Clean(path)
+	i.files[path] = struct{}{}
+	i.AddDir(filepath.Dir(path))
+}
+
+// AddDir indexes a local folder path and its parent folders.
+func (i *Index) AddDir(path string) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, exists := i.dirs[dir]; exists {
+			// parents were indexed along with this folder
+			return
+		}
+
+		i.dirs[dir] = struct{}{}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			return
+		}
+	}
+}
+
+// LocalPath maps a path of the client to its local path.
+func (i *Index) LocalPath(path string) string {
+	return MapPath(path, i.pathMapping)
+}
+
+// LocalPaths maps a path of the client to its local paths within the roots, using the mapping of each root.
+// Paths not mapped into any root are mapped to their local path.
+func (i *Index) LocalPaths(path string) []string {
+	paths := make([]string, 0, 1)
+	for _, r := range i.roots {
+		if p := filepath.Clean(MapPath(path, r.pathMapping)); withinPath(p, r.path) {
+			paths = append(paths, p)
+		}
+	}
+
+	if len(paths) == 0 {
+		paths = append(paths, i.LocalPath(path))
+	}
+
+	return paths
+}
+
+// HasFile returns whether a local path is a torrent file.
+func (i *Index) HasFile(path string) bool {
+	_, exists := i.files[filepath.Clean(path)]
+	return exists
+}
+
+// HasDir returns whether a local path is a folder containing torrent files.
+func (i *Index) HasDir(path string) bool {
+	_, exists := i.dirs[filepath.Clean(path)]
+	return exists
+}
+
+// Files returns the number of indexed files.
+func (i *Index) Files() int {
+	return len(i.files)
+}
+
+// Dirs returns the number of indexed folders.
+func (i *Index) Dirs() int {
+	return len(i.dirs)
+}
+
+/* Helpers */
+
+// MapPath maps a path using the longest mapping whose source is the path itself or one of its parent folders.
+func MapPath(path string, pathMapping map[string]string) string {
+	longest := ""
+	found := false
+	for mapFrom := range pathMapping {
+		if (!found || len(mapFrom) > len(longest)) && withinPath(path, mapFrom) {
+			longest = mapFrom
+			found = true
+		}
+	}
+
+	if !found {
+		return path
+	}
+
+	return joinPath(pathMapping[longest], strings.TrimPrefix(path, strings.TrimSuffix(longest, "/")))
+}
+
+// UnmapPath maps a mapped path back to its source, the reverse of MapPath.
+func UnmapPath(path string, pathMapping map[string]string) string {
+	reversed := make(map[string]string, len(pathMapping))
+	for mapFrom, mapTo := range pathMapping {
+		reversed[mapTo] = mapFrom
+	}
+
+	return MapPath(path, reversed)
+}
+
+// withinPath returns whether path is parent or one of its children, so /data/tv does not match /data/tv2.
+func withinPath(path string, parent string) bool {
+	parent = strings.TrimSuffix(parent, "/")
+	return path == parent || strings.HasPrefix(path, parent+"/")
+}
+
+func joinPath(parent string, rest string) string {
+	if joined := strings.TrimSuffix(parent, "/") + rest; joined != "" {
+		return joined
+	}
+
+	return "/"
+}
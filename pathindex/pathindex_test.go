@@ -0,0 +1,116 @@
+package pathindex
+
+import (
+	"testing"
+
+	"github.com/autobrr/tqm/config"
+)
+
+func TestMapPath(t *testing.T) {
+	pathMapping := map[string]string{
+		"/downloads":         "/mnt/local/downloads",
+		"/downloads/tv":      "/mnt/tv",
+		"/downloads/movies/": "/mnt/movies/",
+		"/":                  "/mnt/root",
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/downloads/tv/Some.Show/file.mkv", "/mnt/tv/Some.Show/file.mkv"},
+		{"/downloads/tv", "/mnt/tv"},
+		// sibling folders sharing a prefix are not within the mapping
+		{"/downloads/tv2/Some.Show/file.mkv", "/mnt/local/downloads/tv2/Some.Show/file.mkv"},
+		{"/downloads/movies/Some.Movie.mkv", "/mnt/movies/Some.Movie.mkv"},
+		{"/downloads/movies", "/mnt/movies"},
+		{"/downloads/moviesx/file.mkv", "/mnt/local/downloads/moviesx/file.mkv"},
+		{"/downloadsx/file.mkv", "/mnt/root/downloadsx/file.mkv"},
+		{"relative/file.mkv", "relative/file.mkv"},
+	}
+
+	for _, tt := range tests {
+		if got := MapPath(tt.path, pathMapping); got != tt.want {
+			t.Errorf("MapPath(%q): expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestUnmapPath(t *testing.T) {
+	pathMapping := map[string]string{
+		"/downloads":    "/mnt/local",
+		"/downloads/tv": "/mnt/local/tv-shows",
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mnt/local/tv-shows/Some.Show/file.mkv", "/downloads/tv/Some.Show/file.mkv"},
+		{"/mnt/local/tv/file.mkv", "/downloads/tv/file.mkv"},
+		{"/mnt/localx/file.mkv", "/mnt/localx/file.mkv"},
+	}
+
+	for _, tt := range tests {
+		if got := UnmapPath(tt.path, pathMapping); got != tt.want {
+			t.Errorf("UnmapPath(%q): expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	idx := New(map[string]config.Torrent{
+		"a": {Files: []string{"/downloads/tv/Some.Show/S01E01.mkv", "/downloads/tv/Some.Show/S01E02.mkv"}},
+		"b": {Files: []string{"/downloads/tv2/Other.Show.mkv"}},
+	}, map[string]string{
+		"/downloads/tv": "/mnt/tv",
+	})
+
+	if !idx.HasFile("/mnt/tv/Some.Show/S01E01.mkv") {
+		t.Error("expected mapped file to be indexed")
+	}
+	if !idx.HasFile("/downloads/tv2/Other.Show.mkv") {
+		t.Error("expected file of sibling folder to be indexed unmapped")
+	}
+	if idx.HasFile("/mnt/tv2/Other.Show.mkv") {
+		t.Error("expected file of sibling folder not to be mapped")
+	}
+
+	for _, dir := range []string{"/mnt/tv/Some.Show", "/mnt/tv", "/mnt", "/", "/downloads/tv2"} {
+		if !idx.HasDir(dir) {
+			t.Errorf("expected folder to be indexed: %q", dir)
+		}
+	}
+	if idx.HasDir("/mnt/tv/Other.Show") {
+		t.Error("expected unrelated folder not to be indexed")
+	}
+
+	if idx.Files() != 3 {
+		t.Errorf("expected 3 files, got %d", idx.Files())
+	}
+}
+
+func TestIndexRoots(t *testing.T) {
+	idx := New(nil, map[string]string{"/data": "/mnt/local"})
+	idx.AddRoot("/mnt/disk1", map[string]string{"/data": "/mnt/disk1"})
+	idx.AddRoot("/mnt/disk2", map[string]string{"/data": "/mnt/disk2"})
+	idx.AddRoot("/mnt/disk3", map[string]string{"/other": "/mnt/disk3"})
+
+	idx.AddTorrent(config.Torrent{Files: []string{"/data/Some.Torrent/file.mkv", "/elsewhere/file.mkv"}})
+
+	// paths are mapped into every root by its own mapping
+	for _, want := range []string{"/mnt/disk1/Some.Torrent/file.mkv", "/mnt/disk2/Some.Torrent/file.mkv",
+		"/elsewhere/file.mkv"} {
+		if !idx.HasFile(want) {
+			t.Errorf("expected file to be indexed: %q", want)
+		}
+	}
+
+	// the client mapping is only used for paths not mapped into a root
+	if idx.HasFile("/mnt/local/Some.Torrent/file.mkv") {
+		t.Error("expected path mapped into roots not to be mapped by the client mapping")
+	}
+	if idx.Files() != 3 {
+		t.Errorf("expected 3 files, got %d", idx.Files())
+	}
+}
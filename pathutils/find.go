@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/autobrr/tqm/logger"
@@ -89,3 +90,13 @@ func GetPathsInFolder(folder string, includeFiles bool, includeFolders bool, acc
 
 	return paths, size
 }
+
+// IsWithin returns whether path is dir or located within dir.
+func IsWithin(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+)
+
+/* Const */
+
+const (
+	Version = 1
+
+	ActionRemove  = "remove"
+	ActionRelabel = "relabel"
+	ActionRetag   = "retag"
+)
+
+/* Structs */
+
+type Plan struct {
+	Version int       `json:"version"`
+	Client  string    `json:"client"`
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+	Actions []Action  `json:"actions"`
+}
+
+type Action struct {
+	Hash   string `json:"hash"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Rule   string `json:"rule,omitempty"`
+	Reason string `json:"reason"`
+	Bytes  int64  `json:"bytes"`
+
+	// relabel
+	Label    string `json:"label,omitempty"`
+	Hardlink bool   `json:"hardlink,omitempty"`
+
+	// retag
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+
+	// state of the torrent when planned
+	State State `json:"state"`
+}
+
+// State holds the details of a torrent that must not change between planning and applying an action.
+type State struct {
+	Path            string   `json:"path"`
+	Label           string   `json:"label"`
+	Tags            []string `json:"tags"`
+	TotalBytes      int64    `json:"total_bytes"`
+	DownloadedBytes int64    `json:"downloaded_bytes"`
+	TrackerStatus   string   `json:"tracker_status"`
+}
+
+/* Initializer */
+
+func New(client string, command string, actions []Action) *Plan {
+	if actions == nil {
+		actions = make([]Action, 0)
+	}
+
+	return &Plan{
+		Version: Version,
+		Client:  client,
+		Command: command,
+		Created: time.Now(),
+		Actions: actions,
+	}
+}
+
+func NewState(t *config.Torrent) State {
+	tags := slices.Clone(t.Tags)
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	slices.Sort(tags)
+
+	return State{
+		Path:            t.Path,
+		Label:           t.Label,
+		Tags:            tags,
+		TotalBytes:      t.TotalBytes,
+		DownloadedBytes: t.DownloadedBytes,
+		TrackerStatus:   t.TrackerStatus,
+	}
+}
+
+/* Public */
+
+func Load(path string) (*Plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	p := new(Plan)
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if p.Version != Version {
+		return nil, fmt.Errorf("unsupported plan version: %d", p.Version)
+	}
+
+	return p, nil
+}
+
+func (p *Plan) Save(path string) error {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// Changes returns the fields that differ between the planned state and the current state.
+func (s State) Changes(current State) []string {
+	changes := make([]string, 0)
+
+	if s.Path != current.Path {
+		changes = append(changes, fmt.Sprintf("path: %q -> %q", s.Path, current.Path))
+	}
+	if s.Label != current.Label {
+		changes = append(changes, fmt.Sprintf("label: %q -> %q", s.Label, current.Label))
+	}
+	if !slices.Equal(s.Tags, current.Tags) {
+		changes = append(changes, fmt.Sprintf("tags: %q -> %q", s.Tags, current.Tags))
+	}
+	if s.TotalBytes != current.TotalBytes {
+		changes = append(changes, fmt.Sprintf("total bytes: %d -> %d", s.TotalBytes, current.TotalBytes))
+	}
+	if s.DownloadedBytes != current.DownloadedBytes {
+		changes = append(changes, fmt.Sprintf("downloaded bytes: %d -> %d", s.DownloadedBytes,
+			current.DownloadedBytes))
+	}
+	if s.TrackerStatus != current.TrackerStatus {
+		changes = append(changes, fmt.Sprintf("tracker status: %q -> %q", s.TrackerStatus, current.TrackerStatus))
+	}
+
+	return changes
+}
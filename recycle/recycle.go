@@ -0,0 +1,178 @@
+package recycle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/pathindex"
+)
+
+/* Const */
+
+// DateLayout names the dated folders of a recycle bin.
+const DateLayout = "2006-01-02"
+
+/* Struct */
+
+// Bin moves removed files into dated folders of its path, instead of deleting them.
+type Bin struct {
+	Path string
+	// locations of paths within the root (the download path) are kept within the dated folders
+	Root string
+	// paths of the client are mapped to local paths before being moved
+	PathMapping map[string]string
+}
+
+// Entry is a dated folder of a bin.
+type Entry struct {
+	Path string
+	Date time.Time
+}
+
+/* Initializer */
+
+func New(path string, root string, pathMapping map[string]string) *Bin {
+	return &Bin{
+		Path:        path,
+		Root:        root,
+		PathMapping: pathMapping,
+	}
+}
+
+/* Public */
+
+// LocalPath maps a path of the client to its local path.
+func (b *Bin) LocalPath(path string) string {
+	return pathindex.MapPath(path, b.PathMapping)
+}
+
+// Move moves a local path into today's folder of the bin, keeping its location relative to the root.
+// The destination is returned, paths that no longer exist are skipped with an empty destination.
+func (b *Bin) Move(path string) (string, error) {
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("stat: %w", err)
+	}
+
+	dst := filepath.Join(b.Path, time.Now().Format(DateLayout), relativePath(path, b.Root))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("create directory: %w", err)
+	}
+
+	// never overwrite previously recycled paths
+	if _, err := os.Lstat(dst); err == nil {
+		dst = fmt.Sprintf("%s.%d", dst, time.Now().UnixNano())
+	}
+
+	// moving is only supported within the same filesystem
+	if err := os.Rename(path, dst); err != nil {
+		return "", fmt.Errorf("move: %w", err)
+	}
+
+	return dst, nil
+}
+
+// MoveTorrentFiles moves the files of a torrent into the bin and removes directories left empty,
+// up to the root (or the save path of the torrent without a root), returning the number of files moved.
+func (b *Bin) MoveTorrentFiles(files []string, savePath string) (int, error) {
+	moved := 0
+	dirs := make(map[string]bool)
+
+	for _, f := range files {
+		path := b.LocalPath(f)
+
+		dst, err := b.Move(path)
+		if err != nil {
+			return moved, fmt.Errorf("%q: %w", path, err)
+		} else if dst != "" {
+			moved++
+		}
+
+		dirs[filepath.Dir(path)] = true
+	}
+
+	// remove empty directories, deepest first
+	sorted := make([]string, 0, len(dirs))
+	for d := range dirs {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	// never remove directories above the save path when the download path is unknown
+	stop := b.Root
+	if stop == "" {
+		if savePath == "" {
+			return moved, nil
+		}
+		stop = b.LocalPath(savePath)
+	}
+
+	for _, d := range sorted {
+		removeEmptyDirs(d, stop)
+	}
+
+	return moved, nil
+}
+
+// Entries returns the dated folders of the bin, oldest first.
+func (b *Bin) Entries() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(b.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	entries := make([]Entry, 0)
+	for _, e := range dirEntries {
+		if !e.IsDir() {
+			continue
+		}
+
+		date, err := time.ParseInLocation(DateLayout, e.Name(), time.Local)
+		if err != nil {
+			// not created by tqm
+			continue
+		}
+
+		entries = append(entries, Entry{Path: filepath.Join(b.Path, e.Name()), Date: date})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+
+	return entries, nil
+}
+
+/* Private */
+
+func relativePath(path string, root string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+
+	// outside of root, keep the full path
+	return strings.TrimPrefix(filepath.Clean(path), string(filepath.Separator))
+}
+
+func removeEmptyDirs(dir string, root string) {
+	root = filepath.Clean(root)
+
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// only removes empty directories
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
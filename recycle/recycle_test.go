@@ -0,0 +1,40 @@
+package recycle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveTorrentFilesWithoutRoot(t *testing.T) {
+	dir := t.TempDir()
+	downloads := filepath.Join(dir, "downloads")
+	files := []string{
+		filepath.Join(downloads, "Some.Torrent", "Sub", "file.nfo"),
+		filepath.Join(downloads, "Some.Torrent", "file.mkv"),
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatalf("create folder: %v", err)
+		}
+		if err := os.WriteFile(f, []byte(f), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	b := New(filepath.Join(dir, ".recycle"), "", nil)
+	moved, err := b.MoveTorrentFiles(files, downloads)
+	if err != nil {
+		t.Fatalf("move torrent files: %v", err)
+	} else if moved != 2 {
+		t.Errorf("expected 2 files moved, got %d", moved)
+	}
+
+	// the folders of the torrent are removed, up to its save path
+	if _, err := os.Stat(filepath.Join(downloads, "Some.Torrent")); !os.IsNotExist(err) {
+		t.Errorf("expected torrent folder to be removed, got %v", err)
+	}
+	if _, err := os.Stat(downloads); err != nil {
+		t.Errorf("expected save path to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autobrr/tqm/logger"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+/* Structs */
+
+type Job struct {
+	Name     string
+	Spec     string
+	Schedule cron.Schedule
+	Run      func() error
+
+	next time.Time
+}
+
+type Scheduler struct {
+	log    *logrus.Entry
+	groups map[string][]*Job
+	order  []string
+}
+
+/* Initializer */
+
+func New() *Scheduler {
+	return &Scheduler{
+		log:    logger.GetLogger("scheduler"),
+		groups: make(map[string][]*Job),
+	}
+}
+
+/* Public */
+
+// ParseSchedule parses either a duration (e.g. 1h30m) or a standard cron expression (e.g. */30 * * * *).
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d < time.Minute {
+			return nil, fmt.Errorf("interval must be at least 1m: %q", spec)
+		}
+
+		return cron.Every(d), nil
+	}
+
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse schedule: %q: %w", spec, err)
+	}
+
+	return s, nil
+}
+
+// Add adds a job to a group, jobs within a group never run concurrently and are run in the order they were added
+// when due at the same time.
+func (s *Scheduler) Add(group string, job *Job) {
+	if _, ok := s.groups[group]; !ok {
+		s.order = append(s.order, group)
+	}
+
+	s.groups[group] = append(s.groups[group], job)
+}
+
+// Jobs returns the number of scheduled jobs.
+func (s *Scheduler) Jobs() int {
+	count := 0
+	for _, jobs := range s.groups {
+		count += len(jobs)
+	}
+
+	return count
+}
+
+// Run runs the scheduled jobs until ctx is cancelled, waiting for any running jobs to finish before returning.
+func (s *Scheduler) Run(ctx context.Context) {
+	wg := new(sync.WaitGroup)
+
+	for _, group := range s.order {
+		wg.Add(1)
+		go func(group string, jobs []*Job) {
+			defer wg.Done()
+			s.runGroup(ctx, group, jobs)
+		}(group, s.groups[group])
+	}
+
+	wg.Wait()
+}
+
+/* Private */
+
+func (s *Scheduler) runGroup(ctx context.Context, group string, jobs []*Job) {
+	now := time.Now()
+	for _, job := range jobs {
+		job.next = job.Schedule.Next(now)
+		s.log.Infof("Scheduled %s for %s (%s), next run: %s", job.Name, group, job.Spec,
+			job.next.Format(time.RFC3339))
+	}
+
+	for {
+		// determine next due job
+		next := jobs[0].next
+		for _, job := range jobs[1:] {
+			if job.next.Before(next) {
+				next = job.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// run due jobs sequentially
+		for _, job := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if time.Now().Before(job.next) {
+				continue
+			}
+
+			start := time.Now()
+			s.log.Infof("Running %s for %s", job.Name, group)
+			if err := job.Run(); err != nil {
+				s.log.WithError(err).Errorf("Failed running %s for %s after %s", job.Name, group,
+					time.Since(start))
+			} else {
+				s.log.Infof("Finished %s for %s in %s", job.Name, group, time.Since(start))
+			}
+
+			// runs missed while the job was running are skipped
+			job.next = job.Schedule.Next(time.Now())
+			s.log.Debugf("Next run of %s for %s: %s", job.Name, group, job.next.Format(time.RFC3339))
+		}
+	}
+}
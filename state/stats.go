@@ -0,0 +1,230 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+)
+
+/* Const */
+
+const (
+	day = 24 * time.Hour
+
+	// samples are kept long enough to look back 7 days
+	statsRetention = 8 * day
+
+	// RecordInterval is the minimum time between recordings of a client, so commands run one after another
+	// record a single sample
+	RecordInterval = 10 * time.Minute
+)
+
+/* Struct */
+
+// Stats holds the history of torrent statistics, by client and hash.
+type Stats struct {
+	path    string
+	Clients map[string]map[string]*TorrentStats `json:"clients"`
+	// unix time each client was last recorded
+	Recorded map[string]int64 `json:"recorded,omitempty"`
+}
+
+// TorrentStats holds the samples of a torrent, thinned out as they age.
+type TorrentStats struct {
+	// unix time the torrent was first recorded
+	FirstSeen int64 `json:"first_seen"`
+	// unix time uploaded bytes were last seen increasing
+	LastUpload int64    `json:"last_upload,omitempty"`
+	Samples    []Sample `json:"samples"`
+}
+
+type Sample struct {
+	Time     int64   `json:"t"`
+	Uploaded int64   `json:"u"`
+	Ratio    float32 `json:"r"`
+	Seeds    int64   `json:"s"`
+	Peers    int64   `json:"p"`
+}
+
+/* Initializer */
+
+// LoadStats loads the stats file, a missing file results in an empty history.
+func LoadStats(path string) (*Stats, error) {
+	s := &Stats{
+		path:     path,
+		Clients:  make(map[string]map[string]*TorrentStats),
+		Recorded: make(map[string]int64),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if s.Clients == nil {
+		s.Clients = make(map[string]map[string]*TorrentStats)
+	}
+	if s.Recorded == nil {
+		s.Recorded = make(map[string]int64)
+	}
+
+	return s, nil
+}
+
+/* Public */
+
+// Record adds a sample of every torrent of a client and forgets torrents no longer present.
+func (s *Stats) Record(client string, torrents map[string]config.Torrent, now time.Time) {
+	previous := s.Clients[client]
+	current := make(map[string]*TorrentStats, len(torrents))
+
+	for h, t := range torrents {
+		ts, ok := previous[h]
+		if !ok {
+			ts = &TorrentStats{FirstSeen: now.Unix()}
+		}
+
+		sample := newSample(&t, now)
+		if n := len(ts.Samples); n > 0 && sample.Uploaded > ts.Samples[n-1].Uploaded {
+			ts.LastUpload = sample.Time
+		}
+
+		ts.Samples = thinSamples(append(ts.Samples, sample), now.Unix())
+		current[h] = ts
+	}
+
+	s.Clients[client] = current
+	s.Recorded[client] = now.Unix()
+}
+
+// Due returns whether a client was not recorded within the RecordInterval.
+func (s *Stats) Due(client string, now time.Time) bool {
+	recorded, ok := s.Recorded[client]
+	return !ok || now.Sub(time.Unix(recorded, 0)) >= RecordInterval
+}
+
+// Apply sets the fields derived from the history of a torrent of a client.
+func (s *Stats) Apply(client string, t *config.Torrent, now time.Time) {
+	ts, ok := s.Clients[client][t.Hash]
+	if !ok || len(ts.Samples) == 0 {
+		// no history, the torrent is seen for the first time
+		return
+	}
+
+	cur := newSample(t, now)
+	t.StatsHistoryDays = days(now.Unix() - ts.FirstSeen)
+	t.UploadedLast24h = max(cur.Uploaded-ts.sampleAt(now.Add(-day).Unix()).Uploaded, 0)
+
+	week := ts.sampleAt(now.Add(-7 * day).Unix())
+	t.UploadedLast7d = max(cur.Uploaded-week.Uploaded, 0)
+	if d := days(cur.Time - week.Time); d > 0 {
+		t.RatioGainPerDay = (cur.Ratio - week.Ratio) / d
+	}
+
+	lastUpload := ts.FirstSeen
+	if ts.LastUpload > 0 {
+		lastUpload = ts.LastUpload
+	}
+	if cur.Uploaded > ts.Samples[len(ts.Samples)-1].Uploaded {
+		lastUpload = cur.Time
+	}
+	t.DaysSinceLastUpload = days(cur.Time - lastUpload)
+}
+
+func (s *Stats) Save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	// write to a temporary file first, so an interrupted write does not lose the history
+	tmp := s.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+/* Private */
+
+func newSample(t *config.Torrent, now time.Time) Sample {
+	return Sample{
+		Time:     now.Unix(),
+		Uploaded: t.UploadedBytes,
+		Ratio:    t.Ratio,
+		Seeds:    t.Seeds,
+		Peers:    t.Peers,
+	}
+}
+
+// sampleAt returns the latest sample taken at or before the given time, or the oldest sample when the history
+// does not go back that far.
+func (ts *TorrentStats) sampleAt(at int64) Sample {
+	sample := ts.Samples[0]
+	for _, s := range ts.Samples {
+		if s.Time > at {
+			break
+		}
+		sample = s
+	}
+
+	return sample
+}
+
+// thinSamples keeps the first sample of every hour for the last day, the first sample of every 6 hours
+// afterwards and always the latest sample, samples past the retention are dropped.
+func thinSamples(samples []Sample, now int64) []Sample {
+	type bucket struct {
+		size  int64
+		index int64
+	}
+
+	kept := make([]Sample, 0, len(samples))
+	buckets := make(map[bucket]bool)
+
+	for i, s := range samples {
+		age := now - s.Time
+		if age > int64(statsRetention.Seconds()) {
+			continue
+		}
+
+		size := int64(time.Hour.Seconds())
+		if age > int64(day.Seconds()+time.Hour.Seconds()) {
+			size = int64(6 * time.Hour.Seconds())
+		}
+
+		key := bucket{size: size, index: s.Time / size}
+		if buckets[key] && i != len(samples)-1 {
+			continue
+		}
+
+		buckets[key] = true
+		kept = append(kept, s)
+	}
+
+	return kept
+}
+
+func days(seconds int64) float32 {
+	return float32(float64(seconds) / day.Seconds())
+}
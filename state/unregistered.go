@@ -0,0 +1,111 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/autobrr/tqm/config"
+)
+
+/* Struct */
+
+// Unregistered holds when torrents were first seen unregistered, by client and hash.
+type Unregistered struct {
+	path    string
+	Clients map[string]map[string]time.Time `json:"clients"`
+}
+
+/* Initializer */
+
+// LoadUnregistered loads the unregistered state file, a missing file results in an empty state.
+func LoadUnregistered(path string) (*Unregistered, error) {
+	u := &Unregistered{
+		path:    path,
+		Clients: make(map[string]map[string]time.Time),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return u, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if err := json.Unmarshal(b, u); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if u.Clients == nil {
+		u.Clients = make(map[string]map[string]time.Time)
+	}
+
+	return u, nil
+}
+
+/* Public */
+
+// Update records torrents of a client that are unregistered and forgets all other torrents of the client,
+// so torrents seen registered again (or removed) start over when next seen unregistered.
+// Torrents whose check failed keep their state, it returns the number of failed checks.
+func (u *Unregistered) Update(client string, torrents map[string]config.Torrent, now time.Time) int {
+	previous := u.Clients[client]
+	current := make(map[string]time.Time)
+	failed := 0
+
+	for h, t := range torrents {
+		since, seen := previous[h]
+
+		unregistered, err := t.CheckUnregistered()
+		if err != nil {
+			failed++
+			if seen {
+				current[h] = since
+			}
+			continue
+		} else if !unregistered {
+			continue
+		}
+
+		if seen {
+			current[h] = since
+		} else {
+			current[h] = now
+		}
+	}
+
+	u.Clients[client] = current
+	return failed
+}
+
+// Since returns when a torrent of a client was first seen unregistered.
+func (u *Unregistered) Since(client string, hash string) (time.Time, bool) {
+	since, ok := u.Clients[client][hash]
+	return since, ok
+}
+
+func (u *Unregistered) Save() error {
+	b, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	// write to a temporary file first, so an interrupted write does not lose the state
+	tmp := u.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(tmp, u.path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
@@ -1,8 +1,6 @@
 package torrentfilemap
 
 import (
-	"strings"
-
 	"github.com/autobrr/tqm/config"
 )
 
@@ -69,29 +67,6 @@ func (t *TorrentFileMap) NoInstances(torrent config.Torrent) bool {
 	return true
 }
 
-func (t *TorrentFileMap) HasPath(path string, torrentPathMapping map[string]string) bool {
-	// contains check
-	for torrentPath := range t.torrentFileMap {
-		// no torrent path mapping provided
-		if len(torrentPathMapping) == 0 {
-			if strings.Contains(torrentPath, path) {
-				return true
-			}
-
-			continue
-		}
-
-		// iterate mappings checking
-		for mapFrom, mapTo := range torrentPathMapping {
-			if strings.Contains(strings.Replace(torrentPath, mapFrom, mapTo, 1), path) {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
 func (t *TorrentFileMap) RemovePath(path string) {
 	delete(t.torrentFileMap, path)
 }
@@ -0,0 +1,25 @@
+package tracker
+
+import (
+	"time"
+
+	"github.com/autobrr/tqm/metrics"
+)
+
+// instrumented records metrics of the api calls made by a tracker
+type instrumented struct {
+	Interface
+}
+
+func (t *instrumented) IsUnregistered(torrent *Torrent) (error, bool) {
+	start := time.Now()
+	err, ur := t.Interface.IsUnregistered(torrent)
+
+	metrics.Add(metrics.TrackerApiRequests, 1, "tracker", t.Name())
+	metrics.Observe(metrics.TrackerApiRequestSeconds, time.Since(start).Seconds(), "tracker", t.Name())
+	if err != nil {
+		metrics.Add(metrics.TrackerApiErrors, 1, "tracker", t.Name())
+	}
+
+	return err, ur
+}
@@ -9,10 +9,10 @@ func Init(cfg Config) error {
 
 	// load trackers
 	if cfg.BHD.Key != "" {
-		trackers = append(trackers, NewBHD(cfg.BHD))
+		trackers = append(trackers, &instrumented{NewBHD(cfg.BHD)})
 	}
 	if cfg.PTP.User != "" && cfg.PTP.Key != "" {
-		trackers = append(trackers, NewPTP(cfg.PTP))
+		trackers = append(trackers, &instrumented{NewPTP(cfg.PTP)})
 	}
 
 	return nil
@@ -0,0 +1,475 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* Structs */
+
+type Client struct {
+	url      string
+	user     string
+	password string
+	http     *http.Client
+}
+
+type Fault struct {
+	Code   int64
+	String string
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("fault %d: %s", f.Code, f.String)
+}
+
+/* Initializer */
+
+func NewClient(url string, user string, password string, timeout time.Duration) *Client {
+	return &Client{
+		url:      url,
+		user:     user,
+		password: password,
+		http: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+/* Public */
+
+// Call invokes method with the given params, returning the decoded response value.
+// Values are decoded into string, int64, float64, bool, []byte, time.Time,
+// []interface{} and map[string]interface{}.
+func (c *Client) Call(method string, params ...interface{}) (interface{}, error) {
+	body, err := EncodeCall(method, params...)
+	if err != nil {
+		return nil, fmt.Errorf("encode call: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/xml")
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %v: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request %v: unexpected response: %s", method, resp.Status)
+	}
+
+	v, err := DecodeResponse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("request %v: %w", method, err)
+	}
+
+	return v, nil
+}
+
+// Multicall invokes several calls within a single system.multicall request.
+// The result of each call is returned in order, calls that faulted are returned as a *Fault.
+func (c *Client) Multicall(calls [][]interface{}) ([]interface{}, error) {
+	batch := make([]interface{}, 0, len(calls))
+	for _, cl := range calls {
+		if len(cl) == 0 {
+			return nil, errors.New("empty call in multicall")
+		}
+
+		method, ok := cl[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid method name in multicall: %#v", cl[0])
+		}
+
+		params := cl[1:]
+		if params == nil {
+			params = []interface{}{}
+		}
+
+		batch = append(batch, map[string]interface{}{
+			"methodName": method,
+			"params":     params,
+		})
+	}
+
+	v, err := c.Call("system.multicall", batch)
+	if err != nil {
+		return nil, err
+	}
+
+	results, ok := v.([]interface{})
+	if !ok || len(results) != len(calls) {
+		return nil, fmt.Errorf("unexpected multicall response: %T", v)
+	}
+
+	out := make([]interface{}, len(results))
+	for i, r := range results {
+		switch rv := r.(type) {
+		case []interface{}:
+			// successful calls are wrapped in a single element array
+			if len(rv) == 1 {
+				out[i] = rv[0]
+			} else {
+				out[i] = rv
+			}
+		case map[string]interface{}:
+			out[i] = faultFromStruct(rv)
+		default:
+			out[i] = rv
+		}
+	}
+
+	return out, nil
+}
+
+/* Encoding */
+
+func EncodeCall(method string, params ...interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(`<?xml version="1.0"?><methodCall><methodName>`)
+	if err := xml.EscapeText(buf, []byte(method)); err != nil {
+		return nil, err
+	}
+	buf.WriteString(`</methodName><params>`)
+
+	for _, p := range params {
+		buf.WriteString(`<param>`)
+		if err := encodeValue(buf, p); err != nil {
+			return nil, err
+		}
+		buf.WriteString(`</param>`)
+	}
+
+	buf.WriteString(`</params></methodCall>`)
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	buf.WriteString(`<value>`)
+
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString(`<string></string>`)
+	case string:
+		buf.WriteString(`<string>`)
+		if err := xml.EscapeText(buf, []byte(val)); err != nil {
+			return err
+		}
+		buf.WriteString(`</string>`)
+	case bool:
+		if val {
+			buf.WriteString(`<boolean>1</boolean>`)
+		} else {
+			buf.WriteString(`<boolean>0</boolean>`)
+		}
+	case int:
+		encodeInt(buf, int64(val))
+	case int32:
+		encodeInt(buf, int64(val))
+	case int64:
+		encodeInt(buf, val)
+	case float64:
+		buf.WriteString(`<double>` + strconv.FormatFloat(val, 'f', -1, 64) + `</double>`)
+	case []byte:
+		buf.WriteString(`<base64>` + base64.StdEncoding.EncodeToString(val) + `</base64>`)
+	case time.Time:
+		buf.WriteString(`<dateTime.iso8601>` + val.Format("20060102T15:04:05") + `</dateTime.iso8601>`)
+	case []string:
+		buf.WriteString(`<array><data>`)
+		for _, e := range val {
+			if err := encodeValue(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(`</data></array>`)
+	case []interface{}:
+		buf.WriteString(`<array><data>`)
+		for _, e := range val {
+			if err := encodeValue(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(`</data></array>`)
+	case map[string]interface{}:
+		// sort keys for a stable encoding
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString(`<struct>`)
+		for _, k := range keys {
+			buf.WriteString(`<member><name>`)
+			if err := xml.EscapeText(buf, []byte(k)); err != nil {
+				return err
+			}
+			buf.WriteString(`</name>`)
+			if err := encodeValue(buf, val[k]); err != nil {
+				return err
+			}
+			buf.WriteString(`</member>`)
+		}
+		buf.WriteString(`</struct>`)
+	default:
+		return fmt.Errorf("unsupported value type: %T", v)
+	}
+
+	buf.WriteString(`</value>`)
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, v int64) {
+	if v >= math.MinInt32 && v <= math.MaxInt32 {
+		buf.WriteString(`<i4>` + strconv.FormatInt(v, 10) + `</i4>`)
+		return
+	}
+
+	buf.WriteString(`<i8>` + strconv.FormatInt(v, 10) + `</i8>`)
+}
+
+/* Decoding */
+
+func DecodeResponse(r io.Reader) (interface{}, error) {
+	d := xml.NewDecoder(r)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "value":
+			return decodeValue(d)
+		case "fault":
+			if err := seekStart(d, "value"); err != nil {
+				return nil, fmt.Errorf("decode fault: %w", err)
+			}
+
+			v, err := decodeValue(d)
+			if err != nil {
+				return nil, fmt.Errorf("decode fault: %w", err)
+			}
+
+			if s, ok := v.(map[string]interface{}); ok {
+				return nil, faultFromStruct(s)
+			}
+
+			return nil, fmt.Errorf("decode fault: unexpected fault value: %#v", v)
+		}
+	}
+}
+
+func seekStart(d *xml.Decoder, name string) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == name {
+			return nil
+		}
+	}
+}
+
+// decodeValue decodes the contents of a <value> element, consuming its end element.
+func decodeValue(d *xml.Decoder) (interface{}, error) {
+	var text strings.Builder
+	var result interface{}
+	typed := false
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			typed = true
+			result, err = decodeTyped(d, t.Name.Local)
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if !typed {
+				// untyped values are strings
+				return text.String(), nil
+			}
+
+			return result, nil
+		}
+	}
+}
+
+func decodeTyped(d *xml.Decoder, kind string) (interface{}, error) {
+	switch kind {
+	case "array":
+		return decodeArray(d)
+	case "struct":
+		return decodeStruct(d)
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			return parseScalar(kind, text.String())
+		}
+	}
+}
+
+func parseScalar(kind string, s string) (interface{}, error) {
+	switch kind {
+	case "string":
+		return s, nil
+	case "int", "i4", "i8":
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", kind, err)
+		}
+		return v, nil
+	case "boolean":
+		return strings.TrimSpace(s) == "1", nil
+	case "double":
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse double: %w", err)
+		}
+		return v, nil
+	case "base64":
+		v, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("parse base64: %w", err)
+		}
+		return v, nil
+	case "dateTime.iso8601":
+		v, err := time.Parse("20060102T15:04:05", strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("parse dateTime: %w", err)
+		}
+		return v, nil
+	case "nil":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value type: %s", kind)
+}
+
+func decodeArray(d *xml.Decoder) (interface{}, error) {
+	values := make([]interface{}, 0)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "value" {
+				// data element
+				continue
+			}
+
+			v, err := decodeValue(d)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		case xml.EndElement:
+			if t.Name.Local == "array" {
+				return values, nil
+			}
+		}
+	}
+}
+
+func decodeStruct(d *xml.Decoder) (interface{}, error) {
+	values := make(map[string]interface{})
+	name := ""
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				var n string
+				if err := d.DecodeElement(&n, &t); err != nil {
+					return nil, err
+				}
+				name = n
+			case "value":
+				v, err := decodeValue(d)
+				if err != nil {
+					return nil, err
+				}
+				values[name] = v
+			}
+		case xml.EndElement:
+			if t.Name.Local == "struct" {
+				return values, nil
+			}
+		}
+	}
+}
+
+func faultFromStruct(s map[string]interface{}) *Fault {
+	f := &Fault{}
+	if code, ok := s["faultCode"].(int64); ok {
+		f.Code = code
+	}
+	if str, ok := s["faultString"].(string); ok {
+		f.String = str
+	}
+
+	return f
+}
@@ -0,0 +1,215 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"string", `a <b> & "c"`, `a <b> & "c"`},
+		{"empty string", "", ""},
+		{"nil", nil, ""},
+		{"true", true, true},
+		{"false", false, false},
+		{"int", 42, int64(42)},
+		{"negative int32", int32(-7), int64(-7)},
+		{"i8", int64(math.MaxInt32) + 1, int64(math.MaxInt32) + 1},
+		{"negative i8", int64(math.MinInt64), int64(math.MinInt64)},
+		{"double", 1.5, 1.5},
+		{"base64", []byte{0, 1, 2, 255}, []byte{0, 1, 2, 255}},
+		{"dateTime", time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC), time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC)},
+		{"string array", []string{"a", "b"}, []interface{}{"a", "b"}},
+		{"empty array", []interface{}{}, []interface{}{}},
+		{"nested arrays",
+			[]interface{}{"d.hash=", []interface{}{int64(1) << 40, []interface{}{true, "x"}}, []string{}},
+			[]interface{}{"d.hash=", []interface{}{int64(1) << 40, []interface{}{true, "x"}}, []interface{}{}}},
+		{"struct",
+			map[string]interface{}{"methodName": "d.name", "params": []interface{}{"HASH"}},
+			map[string]interface{}{"methodName": "d.name", "params": []interface{}{"HASH"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := EncodeCall("test.method", tt.value)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			got, err := DecodeResponse(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("decode %s: %v", body, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v (%s)", tt.want, got, body)
+			}
+		})
+	}
+}
+
+func TestEncodeUnsupportedType(t *testing.T) {
+	if _, err := EncodeCall("test.method", struct{}{}); err == nil {
+		t.Fatal("expected an error encoding a struct")
+	}
+}
+
+func TestEncodeInt(t *testing.T) {
+	body, err := EncodeCall("test.method", int64(math.MaxInt32), int64(math.MaxInt32)+1)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	if !strings.Contains(string(body), "<i4>2147483647</i4>") || !strings.Contains(string(body), "<i8>2147483648</i8>") {
+		t.Errorf("expected i4 and i8 values, got %s", body)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+	}{
+		{"untyped string", `<methodResponse><params><param><value>untyped</value></param></params></methodResponse>`,
+			"untyped"},
+		{"int", `<methodResponse><params><param><value><int> 12 </int></value></param></params></methodResponse>`,
+			int64(12)},
+		{"i8", `<methodResponse><params><param><value><i8>5000000000</i8></value></param></params></methodResponse>`,
+			int64(5000000000)},
+		{"nil", `<methodResponse><params><param><value><nil/></value></param></params></methodResponse>`,
+			nil},
+		{"nested arrays", `<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+  <value><array><data>
+    <value><string>HASH</string></value>
+    <value><i8>1099511627776</i8></value>
+    <value><array><data></data></array></value>
+  </data></array></value>
+</data></array></value></param></params></methodResponse>`,
+			[]interface{}{[]interface{}{"HASH", int64(1099511627776), []interface{}{}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeResponse(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeFault(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+  <member><name>faultCode</name><value><i4>-506</i4></value></member>
+  <member><name>faultString</name><value><string>Method 'd.nope' not defined</string></value></member>
+</struct></value></fault></methodResponse>`
+
+	_, err := DecodeResponse(strings.NewReader(body))
+
+	var f *Fault
+	if !errors.As(err, &f) {
+		t.Fatalf("expected a fault, got %v", err)
+	}
+
+	if f.Code != -506 || f.String != "Method 'd.nope' not defined" {
+		t.Errorf("unexpected fault: %+v", f)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, body := range []string{
+		``,
+		`<methodResponse><params><param><value><int>abc</int></value></param></params></methodResponse>`,
+		`<methodResponse><params><param><value><unknown>1</unknown></value></param></params></methodResponse>`,
+		`<methodResponse><params><param><value><array><data><value>`,
+	} {
+		if v, err := DecodeResponse(strings.NewReader(body)); err == nil {
+			t.Errorf("expected an error decoding %q, got %#v", body, v)
+		}
+	}
+}
+
+func TestClientMulticall(t *testing.T) {
+	var request []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		request, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+  <value><array><data><value><string>Some.Torrent</string></value></data></array></value>
+  <value><struct>
+    <member><name>faultCode</name><value><i4>-501</i4></value></member>
+    <member><name>faultString</name><value><string>Could not find info-hash.</string></value></member>
+  </struct></value>
+  <value><array><data><value><array><data>
+    <value><array><data><value><string>file.mkv</string></value></data></array></value>
+  </data></array></value></data></array></value>
+</data></array></value></param></params></methodResponse>`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "user", "pass", time.Second)
+	results, err := c.Multicall([][]interface{}{
+		{"d.name", "HASH"},
+		{"d.name", "MISSING"},
+		{"f.multicall", "HASH", "", "f.path="},
+	})
+	if err != nil {
+		t.Fatalf("multicall: %v", err)
+	}
+
+	want := `<methodName>system.multicall</methodName><params><param><value><array><data>` +
+		`<value><struct><member><name>methodName</name><value><string>d.name</string></value></member>` +
+		`<member><name>params</name><value><array><data><value><string>HASH</string></value></data></array></value></member>` +
+		`</struct></value>`
+	if !strings.Contains(string(request), want) {
+		t.Errorf("expected request to contain %s, got %s", want, request)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %#v", results)
+	}
+	if results[0] != "Some.Torrent" {
+		t.Errorf("expected unwrapped name, got %#v", results[0])
+	}
+	if f, ok := results[1].(*Fault); !ok || f.Code != -501 {
+		t.Errorf("expected fault -501, got %#v", results[1])
+	}
+	if want := []interface{}{[]interface{}{"file.mkv"}}; !reflect.DeepEqual(results[2], want) {
+		t.Errorf("expected files %#v, got %#v", want, results[2])
+	}
+}
+
+func TestClientCallError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	if _, err := NewClient(srv.URL, "", "", time.Second).Call("system.client_version"); err == nil {
+		t.Fatal("expected an error for an unauthorized response")
+	}
+}